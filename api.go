@@ -7,6 +7,63 @@ import (
 	ite "github.com/Matej-Chmel/go-any-to-string/internal"
 )
 
+// Convert an array or slice of structs or maps to CSV with a header row
+// according to specified Options. Columns are chosen and ordered by columns,
+// by default they're named by fields and keys of the elements.
+func AnyToCSV(a any, o *Options, columns ...string) (string, error) {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToCSV(o, &val, columns)
+}
+
+// Convert any variable to a Graphviz DOT digraph according to specified
+// Options. Structs, maps, slices and arrays are nodes written as records,
+// pointers and nested values are edges. Values shared by pointers are
+// written as a single node.
+func AnyToDOT(a any, o *Options) string {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToDOT(o, &val)
+}
+
+// Convert any variable to Go source code according to specified Options.
+// Returns the code and sorted paths of packages it references.
+func AnyToGoSyntax(a any, o *Options) (string, []string) {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToGoSyntax(o, &val)
+}
+
+// Convert any variable to JSON according to specified Options
+func AnyToJSON(a any, o *Options) string {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToJSON(o, &val)
+}
+
+// Convert a 2D or 3D numeric array or slice to LaTeX matrices
+// according to specified Options. Other values are written
+// in the default format.
+func AnyToLaTeX(a any, o *Options) string {
+	latexOptions := *o
+	latexOptions.LaTeX = true
+	return AnyToStringCustom(a, &latexOptions)
+}
+
+// Convert a 2D array or slice or an array or slice of structs
+// to a markdown table according to specified Options.
+// Other values are written in a fenced code block.
+func AnyToMarkdown(a any, o *Options) string {
+	markdownOptions := *o
+	markdownOptions.Markdown = true
+	return AnyToStringCustom(a, &markdownOptions)
+}
+
+// Convert a 2D or 3D numeric array or slice to a NumPy array literal
+// according to specified Options. Other values are written
+// in the default format.
+func AnyToNumPy(a any, o *Options) string {
+	numpyOptions := *o
+	numpyOptions.NumPy = true
+	return AnyToStringCustom(a, &numpyOptions)
+}
+
 // Convert any variable to a string
 func AnyToString(a any) string {
 	return AnyToStringCustom(a, NewOptions())
@@ -18,6 +75,30 @@ func AnyToStringCustom(a any, o *Options) string {
 	return ValueToStringCustom(&val, o)
 }
 
+// Convert any struct or map to a TOML document according to specified
+// Options. Values that can't be written in TOML are skipped and reported
+// in the returned error.
+func AnyToTOML(a any, o *Options) (string, error) {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToTOML(o, &val)
+}
+
+// Convert a 2D array or slice or an array, slice or map of structs
+// to a table with borders according to specified Options.
+// Other values are written in the default format.
+func AnyToTable(a any, o *Options) string {
+	tableOptions := *o
+	tableOptions.Table = true
+	return AnyToStringCustom(a, &tableOptions)
+}
+
+// Convert any variable to a tree with each field, key-value pair
+// and element on its own line according to specified Options
+func AnyToTree(a any, o *Options) string {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToTree(o, &val)
+}
+
 // Write any variable to a Writer
 func AnyToWriter(a any, w io.Writer) error {
 	return AnyToWriterCustom(a, NewOptions(), w)
@@ -29,6 +110,22 @@ func AnyToWriterCustom(a any, o *Options, w io.Writer) error {
 	return ValueToWriterCustom(&val, o, w)
 }
 
+// Convert any variable to XML according to specified Options.
+// The root element is named after the type of the variable,
+// elements of arrays and slices are enclosed in it. Control characters
+// other than line breaks and tabs can't be written in XML 1.0,
+// they are replaced by U+FFFD.
+func AnyToXML(a any, o *Options) string {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToXML(o, &val)
+}
+
+// Convert any variable to YAML according to specified Options
+func AnyToYAML(a any, o *Options) string {
+	val := reflect.ValueOf(a)
+	return ite.ConvertToYAML(o, &val)
+}
+
 // Convert Value to string
 func ValueToString(val *reflect.Value) string {
 	return ValueToStringCustom(val, NewOptions())
@@ -36,6 +133,35 @@ func ValueToString(val *reflect.Value) string {
 
 // Convert Value to string according to specified Options
 func ValueToStringCustom(val *reflect.Value, o *Options) string {
+	if o.LaTeX {
+		if res, ok := ite.ConvertToLaTeX(o, val); ok {
+			return res
+		}
+	}
+
+	if o.Markdown {
+		if res, ok := ite.ConvertToMarkdown(o, val); ok {
+			return res
+		}
+
+		// Non-tabular value is written in the default format
+		plainOptions := *o
+		plainOptions.Markdown = false
+		return ite.FencedCodeBlock(ValueToStringCustom(val, &plainOptions))
+	}
+
+	if o.NumPy {
+		if res, ok := ite.ConvertToNumPy(o, val); ok {
+			return res
+		}
+	}
+
+	if o.Table {
+		if res, ok := ite.ConvertToTable(o, val); ok {
+			return res
+		}
+	}
+
 	if ite.IsCompositeType(val) {
 		c := ite.NewCompositeConverter(o, val)
 		return c.ConvertStackToString()
@@ -1,17 +1,33 @@
 package goanytostring_test
 
 import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"go/parser"
 	"io"
+	"math"
 	"os"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 	"unsafe"
 
 	ats "github.com/Matej-Chmel/go-any-to-string"
 )
 
+type CycleMap map[string]CycleMap
+
+type CycleNode struct {
+	value int
+	next  *CycleNode
+}
+
+type CycleSlice []CycleSlice
+
 type Example struct {
 	a int
 	B string
@@ -28,12 +44,62 @@ func (e ExampleCustom) String() string {
 	return fmt.Sprintf("%c -> %c -> %c", e.a, e.b, e.c)
 }
 
+type ExampleError struct {
+	code int
+}
+
+func (e ExampleError) Error() string {
+	return fmt.Sprintf("error %d", e.code)
+}
+
+func (e ExampleError) String() string {
+	return fmt.Sprintf("code %d", e.code)
+}
+
+type ExamplePointer struct {
+	a rune
+}
+
+func (e *ExamplePointer) String() string {
+	return fmt.Sprintf("<%c>", e.a)
+}
+
 type NestedExample struct {
 	Example
 	b string
 	C rune
 }
 
+type TagExample struct {
+	ID       int    `anystring:"id"`
+	Password string `anystring:",redact"`
+	Comment  string `anystring:"note,omitempty"`
+	internal int    `anystring:"-"`
+	Plain    bool
+}
+
+type TOMLConfig struct {
+	Name    string
+	Handler func()
+	Labels  map[string]any
+	Main    TOMLServer
+	Servers []TOMLServer
+}
+
+type TOMLServer struct {
+	Host  string
+	Ports []int
+	Ratio *float64
+}
+
+type XMLExample struct {
+	ID    int    `anystring:"id,attr"`
+	Name  string `anystring:"name,attr,omitempty"`
+	Items []any
+	note  string
+	Next  *XMLExample
+}
+
 type SliceExample struct {
 	bytes []byte
 	ints  []int
@@ -122,6 +188,21 @@ func Test2D(ot *testing.T) {
 	}
 	exp := readFile("test_data/2D.txt")
 	check(data, exp, t)
+
+	o := ats.NewOptions()
+	o.AlignColumns = true
+	check(data, "1  2  3\n4 -5  6\n7  8 -9 0 1", t, o)
+	check([][]any{{"ab", 1, "x"}, {"c", 200}, {nil, 3, "yy", 4}},
+		"ab    1 x\nc   200\nnil   3 yy 4", t, o)
+
+	cycle := [][]any{{1, nil}, {22, 3}}
+	cycle[0][1] = cycle
+	check(cycle, " 1 <cycle [][]interface {}>\n22                        3", t, o)
+
+	o.MaxDepth = 1
+	check([][]int{{1, 2}, {3}}, "...\n...", t, o)
+	o.MaxDepth = 2
+	check([][]any{{1, []int{2}}, {333}}, "  1 ...\n333", t, o)
 }
 
 func Test3D(ot *testing.T) {
@@ -182,6 +263,52 @@ func Test4D(ot *testing.T) {
 	}
 	exp := readFile("test_data/4D.txt")
 	check(data, exp, t)
+
+	o := ats.NewOptions()
+	o.AlignColumns = true
+	exp = readFile("test_data/4D_aligned.txt")
+	check(data, exp, t, o)
+}
+
+func TestArrayDimensions(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.ArrayDimensions = []ats.ArrayDimension{{Start: "[", Sep: " ", End: "]"}}
+
+	check([][]int{{1, 2}, {3, 4}}, "[[1 2] [3 4]]", t, o)
+	check([][][]int{{{1}, {2, 3}}, {}}, "[[[1] [2 3]] []]", t, o)
+	check([]int{1, 2}, "[1 2]", t, o)
+	check(ats.AnyToJSON([][]int{{1}}, o), "[[1]]", t)
+	toml, _ := ats.AnyToTOML(map[string][][]int{"x": {{1}, {2}}}, o)
+	check(toml, "x = [[1], [2]]", t)
+
+	o.MaxElements = 2
+	o.SummaryHead = 1
+	o.SummaryTail = 1
+	check([][]int{{1, 2, 3}, {4}, {5}}, "[[1 ... 3 (len=3)] [...] [5] (len=3)]", t, o)
+
+	o = ats.NewOptions()
+	o.ArrayDimensions = []ats.ArrayDimension{
+		{Start: "(", Sep: ", ", End: ")"},
+		{Start: "{\n", Sep: ",\n", End: "\n}", Indent: true},
+	}
+	check([][][]int{{{1, 2}, {3}}, {{4}}}, strings.Join([]string{
+		"{",
+		"    {",
+		"        (1, 2),",
+		"        (3)",
+		"    },",
+		"    {",
+		"        (4)",
+		"    }",
+		"}",
+	}, "\n"), t, o)
+
+	// Default layout is a preset of dimensions
+	data := [][][][]int{{{{1, 2}, {3}}, {{4}}}, {{{5}}}}
+	o = ats.NewOptions()
+	o.ArrayDimensions = ats.NewArrayDimensions(o, 4)
+	check(data, ats.AnyToString(data), t, o)
 }
 
 func TestArrays(ot *testing.T) {
@@ -200,6 +327,9 @@ func TestArrays(ot *testing.T) {
 	checkPtr([]rune{'A', 'B'}, "&[65 66]", t)
 	checkPtr([]string{"hello", "world"}, "&[hello world]", t)
 
+	check([]int{}, "[]", t)
+	check([0]int{}, "[]", t)
+
 	o := ats.NewOptions()
 	o.ByteAsString = true
 	o.RuneAsString = true
@@ -244,6 +374,54 @@ func TestComplex(ot *testing.T) {
 	check(1.2345+4.3456i, "(1.234+4.346i)", t)
 }
 
+func TestCSV(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	rows := []any{
+		&Example{1, "a,b", 'c'},
+		map[string]any{"B": "x\"y", "d": 1.23456, "e": []int{1, 2}},
+		nil,
+		TagExample{2, "secret", "", 3, true},
+	}
+
+	actual, err := ats.AnyToCSV(rows, o)
+	check(err, "nil", t)
+	check(actual, strings.Join([]string{
+		"a,B,c,d,e,id,Password,note,Plain",
+		`1,"a,b",99,,,,,,`,
+		`,"x""y",,1.235,[1 2],,,,`,
+		",,,,,,,,",
+		",,,,,2,***,,true",
+		"",
+	}, "\n"), t)
+
+	actual, err = ats.AnyToCSV([]SliceExample{}, o, "missing")
+	check(err, "nil", t)
+	check(actual, "missing\n", t)
+
+	actual, _ = ats.AnyToCSV([]*TagExample{}, o)
+	check(actual, "id,Password,note,Plain\n", t)
+	actual, _ = ats.AnyToCSV([]TagExample{{ID: 1}}, o)
+	check(actual, "id,Password,note,Plain\n1,***,,false\n", t)
+
+	actual, err = ats.AnyToCSV(rows[:2], o, "e", "B")
+	check(actual, "e,B\n,\"a,b\"\n[1 2],\"x\"\"y\"\n", t)
+
+	_, err = ats.AnyToCSV(5, o)
+	check(err, "CSV table must be an array or slice, not int", t)
+
+	_, err = ats.AnyToCSV([]any{Example{}, 5}, o)
+	check(err, "CSV row 1 must be a struct or map, not int", t)
+
+	_, err = ats.AnyToCSV(nil, o)
+	check(err, "CSV table must be an array or slice, not nil", t)
+
+	o.Pretty = true
+	o.ShowType = true
+	actual, _ = ats.AnyToCSV([]map[string]any{{"a": []int{1}, "b": Example{1, "x", 'y'}}}, o)
+	check(actual, "a,b\n[1],{1 x 121}\n", t)
+}
+
 func TestCustom(ot *testing.T) {
 	t := newTester(ot)
 	data := ExampleCustom{'A', 'b', 'C'}
@@ -251,6 +429,122 @@ func TestCustom(ot *testing.T) {
 	check(data, "A -> b -> C", t)
 	check(&data, "A -> b -> C", t)
 	check[*ExampleCustom](nil, "nil", t)
+
+	p := ExamplePointer{'P'}
+	values := []ExamplePointer{{'A'}, {'B'}}
+	check(&p, "<P>", t)
+	check(p, "{80}", t)
+	check(values, "[{65} {66}]", t)
+
+	o := ats.NewOptions()
+	o.PointerMethods = true
+	check(p, "<P>", t, o)
+	check(values, "[<A> <B>]", t, o)
+	check(map[ExamplePointer]ExamplePointer{{'K'}: {'V'}}, "{<K>:<V>}", t, o)
+	check(struct{ p ExamplePointer }{p}, "{<P>}", t, o)
+
+	o.IgnoreCustomMethod = true
+	check(values, "[{65} {66}]", t, o)
+}
+
+func TestCycle(ot *testing.T) {
+	t := newTester(ot)
+	node := CycleNode{value: 1}
+	node.next = &node
+	m := CycleMap{}
+	m["self"] = m
+	s := make(CycleSlice, 2)
+	s[0] = s
+
+	check(&node, "&{1 <cycle &CycleNode>}", t)
+	check(node, "{1 &{1 <cycle &CycleNode>}}", t)
+	check(m, "{self:<cycle CycleMap>}", t)
+	check(s, "[<cycle CycleSlice> nil]", t)
+
+	// Shared references that don't form a cycle are converted in full
+	shared := &CycleNode{value: 2}
+	check([]*CycleNode{shared, shared}, "[&{2 nil} &{2 nil}]", t)
+
+	o := ats.NewOptions()
+	o.CycleStart = "#"
+	o.CycleEnd = "#"
+	check(&node, "&{1 #&CycleNode#}", t, o)
+}
+
+func TestDOT(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	node := &CycleNode{value: 1}
+	node.next = node
+	shared := &CycleNode{value: 2}
+
+	check(ats.AnyToDOT(node, o), strings.Join([]string{
+		"digraph {",
+		"    node [shape=record];",
+		`    n0 [label="{CycleNode|<f0> value: 1|<f1> next}"];`,
+		"    n0:f1 -> n0;",
+		"}",
+	}, "\n"), t)
+	check(ats.AnyToDOT([]*CycleNode{shared, shared, nil}, o), strings.Join([]string{
+		"digraph {",
+		"    node [shape=record];",
+		`    n0 [label="{[]*goanytostring_test.CycleNode|<f0> [0]|<f1> [1]|<f2> [2]: nil}"];`,
+		`    n1 [label="{CycleNode|<f0> value: 2|<f1> next: nil}"];`,
+		"    n0:f0 -> n1;",
+		"    n0:f1 -> n1;",
+		"}",
+	}, "\n"), t)
+	check(ats.AnyToDOT(map[string]any{"a|b": []int{1}, "c": "{x}"}, o), strings.Join([]string{
+		"digraph {",
+		"    node [shape=record];",
+		`    n0 [label="{map[string]interface \{\}|<f0> a\|b|<f1> c: \{x\}}"];`,
+		`    n1 [label="{[]int|<f0> [0]: 1}"];`,
+		"    n0:f0 -> n1;",
+		"}",
+	}, "\n"), t)
+	check(ats.AnyToDOT(5, o), "digraph {\n    node [shape=record];\n    n0 [label=\"{int|5}\"];\n}", t)
+	check(ats.AnyToDOT(nil, o), "digraph {\n    node [shape=record];\n    n0 [label=\"{nil}\"];\n}", t)
+	check(ats.AnyToDOT((*CycleNode)(nil), o), strings.Join([]string{
+		"digraph {",
+		"    node [shape=record];",
+		`    n0 [label="{*goanytostring_test.CycleNode|nil}"];`,
+		"}",
+	}, "\n"), t)
+	check(ats.AnyToDOT(TagExample{1, "p", "", 2, false}, ats.NewOptions()), strings.Join([]string{
+		"digraph {",
+		"    node [shape=record];",
+		`    n0 [label="{TagExample|<f0> id: 1|<f1> Password: ***|<f4> Plain: false}"];`,
+		"}",
+	}, "\n"), t)
+}
+
+func TestError(ot *testing.T) {
+	t := newTester(ot)
+	base := errors.New("permission denied")
+	wrapped := fmt.Errorf("open failed: %w", base)
+	joined := fmt.Errorf("cleanup: %w", errors.Join(base, ExampleError{2}))
+
+	check(base, "permission denied", t)
+	check(wrapped, "open failed: permission denied", t)
+	check([]error{ExampleError{1}, nil}, "[code 1 nil]", t)
+
+	o := ats.NewOptions()
+	o.ErrorChain = true
+	check(wrapped, "open failed -> permission denied", t, o)
+	check(joined, "cleanup -> [permission denied, error 2]", t, o)
+	check(errors.Join(base, wrapped), "[permission denied, open failed -> permission denied]", t, o)
+
+	o.ErrorChainSep = ": "
+	o.ErrorListStart = "("
+	o.ErrorListSep = " | "
+	o.ErrorListEnd = ")"
+	check(joined, "cleanup: (permission denied | error 2)", t, o)
+
+	o.ErrorBeforeString = true
+	check(ExampleError{3}, "error 3", t, o)
+
+	o.IgnoreCustomMethod = true
+	check(ExampleError{3}, "{3}", t, o)
 }
 
 func TestFloat(ot *testing.T) {
@@ -284,6 +578,31 @@ func TestFormat(ot *testing.T) {
 	check(m, "map[int]string :: 12:hello - 34:world!", t, o)
 }
 
+func TestFormatter(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.RegisterFormatter(reflect.TypeOf(Example{}), func(v reflect.Value) string {
+		return "example"
+	})
+	ats.RegisterFormatterFor(o, func(d time.Duration) string {
+		return d.String()
+	})
+	ats.RegisterFormatterFor(o, func(e error) string {
+		return "E:" + e.Error()
+	})
+
+	d := 1500 * time.Millisecond
+	check(d, "1.5s", t, o)
+	check(&Example{}, "&example", t, o)
+	check(Example{}, "example", t, o)
+	check([]time.Duration{time.Second, time.Minute}, "[1s 1m0s]", t, o)
+	check(map[time.Duration]Example{time.Hour: {}}, "{1h0m0s:example}", t, o)
+	check(NestedExample{Example{}, "b", 'c'}, "{example b 99}", t, o)
+	check(struct{ err error }{errors.New("x")}, "{E:x}", t, o)
+	check(struct{ err error }{nil}, "{nil}", t, o)
+	check(time.Duration(5), "5", t)
+}
+
 func TestFunc(ot *testing.T) {
 	t := newTester(ot)
 	check(hello, "hello(int) string", t)
@@ -298,11 +617,300 @@ func TestFunc(ot *testing.T) {
 	check(actual, "func1(int) int", t)
 }
 
+func TestGoSyntax(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	goSyntax := func(a any) string {
+		code, _ := ats.AnyToGoSyntax(a, o)
+		return code
+	}
+
+	check(goSyntax([]int{1, 2}), "[]int{1, 2}", t)
+	check(goSyntax([2][2]int{{1, 2}, {3, 4}}), "[2][2]int{{1, 2}, {3, 4}}", t)
+	check(goSyntax(map[string][]int{"a": {1}}), `map[string][]int{"a": {1}}`, t)
+	check(goSyntax("a\"b"), `"a\"b"`, t)
+	check(goSyntax(int8(5)), "int8(5)", t)
+	check(goSyntax(2.0), "2.0", t)
+	check(goSyntax([]any{1, "a", int8(3), nil, []int(nil)}),
+		`[]any{1, "a", int8(3), nil, []int(nil)}`, t)
+	check(goSyntax(&Example{1, "b", 'c'}),
+		`&goanytostring_test.Example{/* a: 1 */ B: "b" /* c: 99 */}`, t)
+	five := 5
+	check(goSyntax(&five), "func() *int { v := 5; return &v }()", t)
+
+	o.GoSyntaxPackage = "github.com/Matej-Chmel/go-any-to-string_test"
+	check(goSyntax(map[string]*Example{"x": {B: "y"}}),
+		`map[string]*Example{"x": {/* a: 0 */ B: "y" /* c: 0 */}}`, t)
+
+	o.GoSyntaxUnexported = true
+	check(goSyntax(NestedExample{Example{1, "b", 'c'}, "d", 'e'}),
+		`NestedExample{Example: Example{a: 1, B: "b", c: 99}, b: "d", C: 101}`, t)
+
+	o.Pretty = true
+	check(goSyntax(map[string]Example{"x": {}}), strings.Join([]string{
+		"map[string]Example{",
+		`    "x": {`,
+		"        a: 0,",
+		`        B: "",`,
+		"        c: 0,",
+		"    },",
+		"}",
+	}, "\n"), t)
+
+	o.Pretty = false
+	data := []any{
+		[]*int{nil}, complex64(1 - 2i), math.Inf(-1), time.Second,
+		&CycleNode{1, nil}, map[Example][]Example{{B: "k"}: {{B: "v"}}},
+		struct{ A []string }{[]string{"a"}}, make(chan int, 2), hello,
+	}
+	code, imports := ats.AnyToGoSyntax(data, o)
+
+	if _, err := parser.ParseExpr(code); err != nil {
+		t.fail(1, "%s\n\n%v", code, err)
+	}
+
+	check(imports, "[math time]", t)
+
+	complexes := []complex128{complex(math.Inf(1), 1), complex(1, math.NaN()), 1 - 2i}
+	code, _ = ats.AnyToGoSyntax(complexes, o)
+	check(code, "[]complex128{complex(math.Inf(1), 1.0), complex(1.0, math.NaN()), (1.0 + -2.0i)}", t)
+
+	if _, err := parser.ParseExpr(code); err != nil {
+		t.fail(1, "%s\n\n%v", code, err)
+	}
+}
+
 func TestInterface(ot *testing.T) {
 	t := newTester(ot)
 	var i interface{}
 	check(i, "nil", t)
 	checkPtr(i, "&nil", t)
+
+	data := []any{1, "x", Example{1, "b", 'c'}, nil, []any{2.5, map[string]any{"k": true}}}
+	check(data, "[1 x {1 b 99} nil [2.5 {k:true}]]", t)
+
+	self := make([]any, 1)
+	self[0] = self
+	check(self, "[<cycle []interface {}>]", t)
+
+	o := ats.NewOptions()
+	o.ShowDynamicType = true
+	check(data[:4], "[1(int) x(string) {1 b 99}(Example) nil]", t, o)
+	check(struct{ a any }{[]int{1}}, "{[1]([]int)}", t, o)
+
+	o.DynamicTypeStart = " <"
+	o.DynamicTypeEnd = ">"
+	check(map[string]any{"k": true}, "{k:true <bool>}", t, o)
+}
+
+func TestJSON(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	node := &CycleNode{1, nil}
+	node.next = node
+
+	tests := []struct {
+		data     any
+		expected string
+	}{
+		{[]int{1, 2}, "[1, 2]"},
+		{[][]int{{1}, {2, 3}}, "[[1], [2, 3]]"},
+		{map[string]int{"b": 2, "a": 1}, `{"a": 1, "b": 2}`},
+		{map[int]bool{1: true}, `{"1": true}`},
+		{&Example{1, "x", 'c'}, `{"a": 1, "B": "x", "c": 99}`},
+		{"a\"b\n\x01", `"a\"b\n\u0001"`},
+		{math.Inf(-1), `"-Inf"`},
+		{[]any{1.5, nil, []int(nil)}, "[1.5, null, null]"},
+		{complex(1, 2), `"(1+2i)"`},
+		{make(chan int), `"chan int"`},
+		{hello, `"hello(int) string"`},
+		{ExampleCustom{'a', 'b', 'c'}, `"a -> b -> c"`},
+		{TagExample{1, "p", "", 2, true}, `{"id": 1, "Password": "***", "Plain": true}`},
+		{node, `{"value": 1, "next": "<cycle &CycleNode>"}`},
+		{map[string]int{}, "{}"},
+	}
+
+	for _, test := range tests {
+		actual := ats.AnyToJSON(test.data, o)
+		check(actual, test.expected, t)
+
+		if !json.Valid([]byte(actual)) {
+			t.fail(1, "invalid JSON %s", actual)
+		}
+	}
+
+	// Cycle markers are quoted like other strings
+	tagged := []struct {
+		A any `json:"a"`
+	}{{nil}}
+	tagged[0].A = tagged
+	check(ats.AnyToJSON(tagged, o), `[{"A": "<cycle []struct { A interface {} \"json:\\\"a\\\"\" }>"}]`, t)
+
+	o.ByteAsString = true
+	o.Pretty = true
+	check(ats.AnyToJSON(map[string]any{"a": []byte("hi"), "b": Example{}}, o), strings.Join([]string{
+		"{",
+		`    "a": "hi",`,
+		`    "b": {`,
+		`        "a": 0,`,
+		`        "B": "",`,
+		`        "c": 0`,
+		"    }",
+		"}",
+	}, "\n"), t)
+
+	// Options of other output formats don't apply
+	o = ats.NewOptions()
+	o.Markdown = true
+	o.Table = true
+	check(ats.AnyToJSON([][]int{{1}}, o), "[[1]]", t)
+}
+
+func TestLaTeX(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.LaTeX = true
+
+	check([][]float64{{1, -2.5}, {10, 0.125}}, strings.Join([]string{
+		`\begin{bmatrix}`,
+		` 1.0 &  -2.5 \\`,
+		`10.0 & 0.125`,
+		`\end{bmatrix}`,
+	}, "\n"), t, o)
+
+	o.FloatDecimalPlaces = 1
+	o.LaTeXMatrix = "pmatrix"
+	check([2][1]complex128{{1 + 2i}, {3.25 - 4i}}, strings.Join([]string{
+		`\begin{pmatrix}`,
+		`  1+2i \\`,
+		`3.2-4i`,
+		`\end{pmatrix}`,
+	}, "\n"), t, o)
+
+	check([][][]int{{{1, 2}}, {{30, 4}}}, strings.Join([]string{
+		`\begin{pmatrix}`,
+		` 1 & 2`,
+		`\end{pmatrix},`,
+		`\begin{pmatrix}`,
+		`30 & 4`,
+		`\end{pmatrix}`,
+	}, "\n"), t, o)
+
+	check([][]float64{{math.Inf(1), 1}, {math.NaN(), math.Inf(-1)}}, strings.Join([]string{
+		`\begin{pmatrix}`,
+		`      \infty &     1.0 \\`,
+		`\mathrm{NaN} & -\infty`,
+		`\end{pmatrix}`,
+	}, "\n"), t, o)
+
+	// Other values are written in the default format
+	check([]string{"a", "b"}, "[a b]", t, o)
+	check([]int{1, 2}, "[1 2]", t, o)
+	check([][]complex128{{complex(math.Inf(1), 0)}}, "(+Inf+0i)", t, o)
+}
+
+func TestLength(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.ShowLen = true
+	slice := make([]int, 3, 8)
+	slice[0] = 1
+	channel := make(chan int, 10)
+	channel <- 1
+	channel <- 2
+
+	check(slice, "[1 0 0](len=3 cap=8)", t, o)
+	check(channel, "chan int(len=2 cap=10)", t, o)
+	check([2]string{"ab", ""}, "[ab(len=2) (len=0)](len=2)", t, o)
+	check(map[int]bool{1: true}, "{1:true}(len=1)", t, o)
+	check([][]int{{1, 2}, {3}}, "1 2(len=2 cap=2)\n3(len=1 cap=1)(len=2 cap=2)", t, o)
+	check(ats.AnyToJSON([]string{"x"}, o), `["x"]`, t)
+	check(ats.AnyToMarkdown([][]any{{"ab", []int{1}}}, o), strings.Join([]string{
+		"| 0 | 1 |",
+		"| --- | --- |",
+		"| ab | [1] |",
+	}, "\n"), t)
+
+	// Annotations replace summaries
+	o.MaxElements = 2
+	o.SummaryHead = 1
+	o.SummaryTail = 1
+	check([]int{1, 2, 3}, "[1 ... 3](len=3 cap=3)", t, o)
+
+	o = ats.NewOptions()
+	o.ByteAsString = true
+	o.CapSep = "/"
+	o.LenEnd = ">"
+	o.LenStart = "<"
+	o.ShowLen = true
+	check(append(make([]byte, 0, 4), "hi"...), "hi<2/4>", t, o)
+}
+
+func TestLineWidth(ot *testing.T) {
+	t := newTester(ot)
+	data := struct {
+		Name string
+		List []int
+		Map  map[string]Example
+		Grid [][]int
+	}{"abc", []int{1, 2, 3, 4, 5, 6, 7, 8}, map[string]Example{"k": {1, "x", 'c'}}, [][]int{{1}, {2}}}
+
+	o := ats.NewOptions()
+	o.LineWidth = 20
+	o.ShowFieldNames = true
+
+	check(data, strings.Join([]string{
+		"{",
+		"    Name:abc",
+		"    List:[",
+		"        1",
+		"        2",
+		"        3",
+		"        4",
+		"        5",
+		"        6",
+		"        7",
+		"        8",
+		"    ]",
+		"    Map:{",
+		"        k:{",
+		"            a:1",
+		"            B:x",
+		"            c:99",
+		"        }",
+		"    }",
+		"    Grid:",
+		"        1",
+		"        2",
+		"}",
+	}, "\n"), t, o)
+
+	o.LineWidth = 40
+	o.PrettyIndent = "  "
+	check(data, strings.Join([]string{
+		"{",
+		"  Name:abc",
+		"  List:[1 2 3 4 5 6 7 8]",
+		"  Map:{k:{a:1 B:x c:99}}",
+		"  Grid:",
+		"    1",
+		"    2",
+		"}",
+	}, "\n"), t, o)
+
+	check([]int{1, 2, 3}, "[1 2 3]", t, o)
+	check(Example{}, "{a:0 B: c:0}", t, o)
+	check(struct{}{}, "{}", t, o)
+	check([]int{}, "[]", t, o)
+
+	// Text after the end of a group must fit on the line as well
+	o = ats.NewOptions()
+	o.LineWidth = 31
+	o.ShowLen = true
+	lengths := map[string][]int{"k": {1, 2}}
+	check(lengths, "{\n    k(len=1):[1 2](len=2 cap=2)\n}(len=1)", t, o)
+	o.LineWidth = 30
+	check(lengths, "{\n    k(len=1):[\n        1\n        2\n    ](len=2 cap=2)\n}(len=1)", t, o)
 }
 
 func TestMap(ot *testing.T) {
@@ -316,6 +924,76 @@ func TestMap(ot *testing.T) {
 
 	actual = ats.AnyToString(s)
 	check(actual, "{F:&false T:&true}", t)
+
+	check(map[int]int{}, "{}", t)
+
+	// Maps in the last layer of an array start with their keys
+	check([]map[string]int{{"a": 1, "b": 2}, {}}, "[{a:1 b:2} {}]", t)
+	check([][]map[int][]int{{{1: {2}}}, {nil}}, "{1:[2]}\nnil", t)
+}
+
+func TestMarkdown(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.Markdown = true
+
+	check([][]any{{1, "a|b"}, {2.5}, {3, "c\nd", nil}}, strings.Join([]string{
+		"| 0 | 1 | 2 |",
+		"| ---: | --- | --- |",
+		`| 1 | a\|b |  |`,
+		"| 2.5 |  |  |",
+		"| 3 | c<br>d | nil |",
+	}, "\n"), t, o)
+
+	check([]*TagExample{{1, "p", "", 2, true}, nil, {3, "", "hi", 4, false}},
+		strings.Join([]string{
+			"| id | Password | note | Plain |",
+			"| ---: | --- | --- | --- |",
+			"| 1 | *** |  | true |",
+			"|  |  |  |  |",
+			"| 3 | *** | hi | false |",
+		}, "\n"), t, o)
+
+	check([]Example{{1, "x", 'c'}}, strings.Join([]string{
+		"| a | B | c |",
+		"| ---: | --- | ---: |",
+		"| 1 | x | 99 |",
+	}, "\n"), t, o)
+
+	check([]int{1, 2}, "```\n[1 2]\n```", t, o)
+	check(map[string]Example{"k": {1, "x", 'c'}}, "```\n{k:{1 x 99}}\n```", t, o)
+	check("a ``` b", "````\na ``` b\n````", t, o)
+	check([][]int{}, "```\n[]\n```", t, o)
+	check([][]int{{}, nil}, "```\n[]\n```", t, o)
+	check([]struct{}{}, "```\n[]\n```", t, o)
+	check([]Example(nil), "| a | B | c |\n| --- | --- | --- |", t, o)
+	check([]*TagExample{}, "| id | Password | note | Plain |\n| --- | --- | --- | --- |", t, o)
+}
+
+func TestMaxDepth(ot *testing.T) {
+	t := newTester(ot)
+	b := NestedExample{Example{34, "world", '%'}, "super", 'X'}
+	m := map[string][]int{"a": {1, 2}}
+	node := &CycleNode{value: 1, next: &CycleNode{value: 2}}
+
+	o := ats.NewOptions()
+	o.MaxDepth = 1
+	o.RuneAsString = true
+
+	check(b, "{... super X}", t, o)
+	check(m, "{a:...}", t, o)
+	check([][]int{{1, 2}, {3}}, "...\n...", t, o)
+	check(node, "&...", t, o)
+	check([]string{"hello", "world"}, "[hello world]", t, o)
+	check([][]rune{[]rune("ab"), []rune("cd")}, "ab\ncd", t, o)
+
+	o.MaxDepth = 2
+	check(b, "{{34 world %} super X}", t, o)
+	check(node, "&{1 ...}", t, o)
+
+	o.Elision = "<>"
+	o.MaxDepth = 3
+	check(node, "&{1 &<>}", t, o)
 }
 
 func TestMemory(ot *testing.T) {
@@ -327,6 +1005,77 @@ func TestMemory(ot *testing.T) {
 	checkPtr(unsafe.Pointer(uintptr(0x34125678)), "&Ux34125678", t)
 }
 
+func TestNumPy(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.NumPy = true
+
+	check([][]int{{1, -20}, {300, 4}}, strings.Join([]string{
+		"array([[  1, -20],",
+		"       [300,   4]])",
+	}, "\n"), t, o)
+
+	check([][][]float32{{{1, 2}}, {{3.5, 4}}}, strings.Join([]string{
+		"array([[[1.0, 2.0]],",
+		"",
+		"       [[3.5, 4.0]]])",
+	}, "\n"), t, o)
+
+	check([][]complex64{{1 - 1i, 2}}, "array([[1-1j, 2+0j]])", t, o)
+	check([][]float64{{math.NaN(), math.Inf(-1), 1.5}}, "array([[np.nan, -np.inf, 1.5]])", t, o)
+	check(&[][][]int{{{1}}}, "array([[[1]]])", t, o)
+	check([][]int{{1, 2}, {3}}, "array([[1, 2],\n       [3]], dtype=object)", t, o)
+	check([][][]int{{{1}}, {{2}, {3}}}, strings.Join([]string{
+		"array([[[1]],",
+		"",
+		"       [[2],",
+		"        [3]]], dtype=object)",
+	}, "\n"), t, o)
+
+	o.ByteAsString = true
+	check([][]byte{{'a'}}, "a", t, o)
+}
+
+func TestPointerLabels(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.PointerLabels = true
+	node := &CycleNode{value: 1}
+	node.next = node
+	shared := &CycleNode{value: 2}
+
+	check(node, "#1=&{1 #1}", t, o)
+	check(*node, "{1 #1=&{1 #1}}", t, o)
+	check([]*CycleNode{shared, shared, {value: 3}}, "[#1=&{2 nil} #1 &{3 nil}]", t, o)
+	check(map[string]any{"a": shared, "b": []any{shared, node}},
+		"{a:#1=&{2 nil} b:[#1 #2=&{1 #2}]}", t, o)
+	check(ats.AnyToJSON([]*CycleNode{shared, shared}, o),
+		`[{"value": 2, "next": null}, {"value": 2, "next": null}]`, t)
+
+	o.LabelSep = ":"
+	o.LabelStart = "@"
+	check([2]*CycleNode{shared, shared}, "[@1:&{2 nil} @1]", t, o)
+
+	// References that aren't written aren't counted
+	type holder struct{ node *CycleNode }
+	ats.RegisterFormatterFor(o, func(h holder) string { return "holder" })
+	check([]any{shared, holder{shared}}, "[&{2 nil} holder]", t, o)
+	o.MaxDepth = 3
+	check([]any{shared, []any{[]any{shared}}}, "[&{2 nil} [[...]]]", t, o)
+	o.MaxElements = 2
+	o.SummaryHead = 1
+	o.SummaryTail = 1
+	check([]*CycleNode{shared, {value: 9}, shared, {value: 8}},
+		"[&{2 nil} ... &{8 nil}] (len=4)", t, o)
+
+	o = ats.NewOptions()
+	o.PointerAddresses = true
+	addr := fmt.Sprintf("%p", shared)
+	check([]*CycleNode{shared, shared}, "["+addr+"=&{2 nil} "+addr+"=&{2 nil}]", t, o)
+	check(ats.AnyToJSON([]*CycleNode{shared, shared}, o),
+		`[{"value": 2, "next": null}, {"value": 2, "next": null}]`, t)
+}
+
 func TestPointers(ot *testing.T) {
 	t := newTester(ot)
 	checkPtr(false, "&false", t)
@@ -354,6 +1103,79 @@ func TestPointers(ot *testing.T) {
 	checkPtr('A', "&A", t, o)
 }
 
+func TestPretty(ot *testing.T) {
+	t := newTester(ot)
+	data := struct {
+		Name string
+		Grid [][]int
+		Tags map[string][]int
+		Ptr  *Example
+		None struct{}
+	}{"x", [][]int{{1, 2}, {3, 4}}, map[string][]int{"a": {1}}, &Example{1, "b", 'c'}, struct{}{}}
+
+	o := ats.NewOptions()
+	o.Pretty = true
+	o.ShowFieldNames = true
+
+	check(data, strings.Join([]string{
+		"{",
+		"    Name:x",
+		"    Grid:",
+		"        1 2",
+		"        3 4",
+		"    Tags:{",
+		"        a:[1]",
+		"    }",
+		"    Ptr:&{",
+		"        a:1",
+		"        B:b",
+		"        c:99",
+		"    }",
+		"    None:{}",
+		"}",
+	}, "\n"), t, o)
+
+	o.PrettyIndent = "\t"
+	o.ShowFieldNames = false
+	check(map[int][][][][]int{1: {{{{1, 2}}}, {{{3}}}}}, strings.Join([]string{
+		"{",
+		"\t1:",
+		"\t\t[",
+		"\t\t\t1 2",
+		"\t\t]",
+		"\t\t[",
+		"\t\t\t3",
+		"\t\t]",
+		"}",
+	}, "\n"), t, o)
+
+	check([][]int{{1}, {2}}, "1\n2", t, o)
+	check(map[int]int{}, "{}", t, o)
+
+	o = ats.NewOptions()
+	o.ArrayIndent = "  "
+	o.Pretty = true
+	check(map[string]string{"a": "x\ny"}, "{\n    a:x\ny\n}", t, o)
+	check(struct{ A [][]int }{[][]int{{1, 2}, {3}}}, "{\n        1 2\n        3\n}", t, o)
+
+	o.LineWidth = 5
+	o.Pretty = false
+	check(map[string]string{"a": "x\ny"}, "{\n    a:x\ny\n}", t, o)
+	check(struct{ A [][]int }{[][]int{{1, 2}, {3}}}, "{\n        1 2\n        3\n}", t, o)
+
+	// PrettyIndent takes precedence over ArrayIndent
+	arrays := [][][][]int{{{{1}}}}
+	o = ats.NewOptions()
+	o.ArrayIndent = "-"
+	o.PrettyIndent = "+"
+	check(arrays, "[\n-1\n]", t, o)
+	o.Pretty = true
+	check(arrays, "[\n+1\n]", t, o)
+	o.Pretty = false
+	o.LineWidth = 1
+	check(arrays, "[\n+1\n]", t, o)
+}
+
 func TestStruct(ot *testing.T) {
 	t := newTester(ot)
 	a := Example{12, "hello", '*'}
@@ -383,6 +1205,391 @@ func TestStruct(ot *testing.T) {
 	checkPtr(c, "&{bytes:hello world ints:[1 2 3]}", t, o)
 }
 
+func TestSummary(ot *testing.T) {
+	t := newTester(ot)
+	a := make([]int, 10)
+
+	for i := range a {
+		a[i] = i
+	}
+
+	m := map[int]string{1: "a", 2: "b", 3: "c", 4: "d", 5: "e"}
+
+	o := ats.NewOptions()
+	o.MaxElements = 5
+	o.MaxEntries = 4
+
+	check(a, "[0 1 2 ... 7 8 9] (len=10)", t, o)
+	check(a[:5], "[0 1 2 3 4]", t, o)
+	// Head and tail cover the whole map
+	check(m, "{1:a 2:b 3:c 4:d 5:e}", t, o)
+
+	o.SummaryHead = 2
+	o.SummaryTail = 1
+	check(m, "{1:a 2:b ... 5:e} (len=5)", t, o)
+	check([][]int{a, a, a, a, a, a}, strings.Join([]string{
+		"0 1 ... 9 (len=10)",
+		"0 1 ... 9 (len=10)",
+		"...",
+		"0 1 ... 9 (len=10) (len=6)",
+	}, "\n"), t, o)
+
+	o.SummaryHead = 0
+	o.SummaryStart = " #"
+	o.SummaryEnd = ""
+	check(a, "[... 9] #10", t, o)
+
+	o.SummaryTail = 0
+	check(m, "{...} #5", t, o)
+
+	// Negative counts are written as 0
+	o.SummaryHead = -1
+	o.SummaryTail = -2
+	check(m, "{...} #5", t, o)
+	check(a, "[...] #10", t, o)
+}
+
+func TestTable(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	o.Table = true
+
+	check([][]any{{1, "日本語"}, {22.5, "e\u0301x\nline"}, {3}}, strings.Join([]string{
+		"+------+--------+",
+		"|    0 | 1      |",
+		"+------+--------+",
+		"|    1 | 日本語 |",
+		"| 22.5 | e\u0301x     |",
+		"|      | line   |",
+		"|    3 |        |",
+		"+------+--------+",
+	}, "\n"), t, o)
+
+	o.FloatDecimalPlaces = 1
+	o.TableRowIndices = true
+	check([]*TagExample{{1, "p", "", 2, true}, nil}, strings.Join([]string{
+		"+---+----+----------+------+-------+",
+		"|   | id | Password | note | Plain |",
+		"+---+----+----------+------+-------+",
+		"| 0 |  1 | ***      |      | true  |",
+		"| 1 |    |          |      |       |",
+		"+---+----+----------+------+-------+",
+	}, "\n"), t, o)
+
+	o.TableRowIndices = false
+	o.TableUnicode = true
+	check(map[string]struct {
+		A []int
+		b float64
+		c string
+	}{"k": {[]int{1}, 2.25, "x"}}, strings.Join([]string{
+		"┌───┬─────┬─────┬───┐",
+		"│   │ A   │   b │ c │",
+		"├───┼─────┼─────┼───┤",
+		"│ k │ [1] │ 2.2 │ x │",
+		"└───┴─────┴─────┴───┘",
+	}, "\n"), t, o)
+
+	check([]int{1, 2}, "[1 2]", t, o)
+	check([][]int{}, "[]", t, o)
+	check([][]int{{}, nil}, "[]", t, o)
+	check([]struct{}{}, "[]", t, o)
+	check([]Example(nil), strings.Join([]string{
+		"┌───┬───┬───┐",
+		"│ a │ B │ c │",
+		"├───┼───┼───┤",
+		"└───┴───┴───┘",
+	}, "\n"), t, o)
+}
+
+func TestTags(ot *testing.T) {
+	t := newTester(ot)
+	a := TagExample{1, "secret", "", 5, true}
+	b := TagExample{2, "", "hi", 6, false}
+
+	check(a, "{1 *** true}", t)
+	check(b, "{2 *** hi false}", t)
+	check(struct{}{}, "{}", t)
+	check(struct {
+		a int `anystring:"-"`
+	}{1}, "{}", t)
+
+	o := ats.NewOptions()
+	o.ShowFieldNames = true
+	check(a, "{id:1 Password:*** Plain:true}", t, o)
+	check(&b, "&{id:2 Password:*** note:hi Plain:false}", t, o)
+
+	o.RedactedValue = "<hidden>"
+	o.TagName = "json"
+	check(a, "{ID:1 Password:secret Comment: internal:5 Plain:true}", t, o)
+	check(struct {
+		A int `json:"a"`
+	}{3}, "{a:3}", t, o)
+
+	o.TagName = ""
+	check(struct {
+		A int `anystring:"-"`
+	}{3}, "{A:3}", t, o)
+}
+
+func TestTOML(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	ratio := 0.5
+	config := TOMLConfig{
+		Name:    "app",
+		Handler: func() {},
+		Labels:  map[string]any{"a b": 1, "list": []any{1, nil, "x"}},
+		Main:    TOMLServer{"main", nil, &ratio},
+		Servers: []TOMLServer{{"a", []int{1, 2}, nil}, {"b", []int{3}, nil}},
+	}
+
+	actual, err := ats.AnyToTOML(config, o)
+	check(actual, strings.Join([]string{
+		`Name = "app"`,
+		"",
+		"[Labels]",
+		`"a b" = 1`,
+		`list = [1, "x"]`,
+		"",
+		"[Main]",
+		`Host = "main"`,
+		"Ports = []",
+		"Ratio = 0.5",
+		"",
+		"[[Servers]]",
+		`Host = "a"`,
+		"Ports = [1, 2]",
+		"",
+		"[[Servers]]",
+		`Host = "b"`,
+		"Ports = [3]",
+	}, "\n"), t)
+
+	check(fmt.Sprint(err), strings.Join([]string{
+		"Handler: func can't be written in TOML",
+		"Labels.list[1]: nil can't be written in TOML",
+		"Servers[0].Ratio: nil can't be written in TOML",
+		"Servers[1].Ratio: nil can't be written in TOML",
+	}, "\n"), t)
+
+	actual, _ = ats.AnyToTOML(map[string]Example{"x": {1, "b", 'c'}}, o)
+	check(actual, "[x]\na = 1\nB = \"b\"\nc = 99", t)
+	actual, _ = ats.AnyToTOML(map[string][]any{"x": {1.0, math.NaN(), TagExample{ID: 1}}}, o)
+	check(actual, `x = [1.0, nan, {id = 1, Password = "***", Plain = false}]`, t)
+
+	cycle := []any{1, nil}
+	cycle[1] = cycle
+	actual, _ = ats.AnyToTOML(map[string]any{"x": cycle}, o)
+	check(actual, `x = [1, "<cycle []interface {}>"]`, t)
+
+	if _, err := ats.AnyToTOML(5, o); err == nil {
+		t.fail(1, "expected an error for a document that isn't a table")
+	}
+
+	_, err = ats.AnyToTOML(nil, o)
+	check(fmt.Sprint(err), "TOML document must be a struct or map, not nil", t)
+
+	actual, err = ats.AnyToTOML(map[string]any{
+		"big": uint64(math.MaxUint64), "list": [][]any{{1, nil}}, "max": uint64(math.MaxInt64),
+	}, o)
+	check(actual, "list = [[1]]\nmax = 9223372036854775807", t)
+	check(fmt.Sprint(err), strings.Join([]string{
+		"big: 18446744073709551615 can't be written in TOML",
+		"list[0][1]: nil can't be written in TOML",
+	}, "\n"), t)
+}
+
+func TestTree(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	ratio := 0.5
+	config := TOMLConfig{
+		Name:    "app",
+		Labels:  map[string]any{"a b": 1, "e": map[int]int{}, "list": []any{1, nil}},
+		Main:    TOMLServer{"main", []int{}, &ratio},
+		Servers: []TOMLServer{{"a", []int{1, 2}, nil}},
+	}
+
+	check(ats.AnyToTree(config, o), strings.Join([]string{
+		"TOMLConfig",
+		"├── Name: app",
+		"├── Handler: nil",
+		"├── Labels",
+		"│   ├── a b: 1",
+		"│   ├── e: {}",
+		"│   └── list",
+		"│       ├── [0]: 1",
+		"│       └── [1]: nil",
+		"├── Main",
+		"│   ├── Host: main",
+		"│   ├── Ports: []",
+		"│   └── Ratio: 0.5",
+		"└── Servers",
+		"    └── [0]",
+		"        ├── Host: a",
+		"        ├── Ports",
+		"        │   ├── [0]: 1",
+		"        │   └── [1]: 2",
+		"        └── Ratio: nil",
+	}, "\n"), t)
+
+	node := &CycleNode{1, nil}
+	node.next = node
+	check(ats.AnyToTree(node, o), "CycleNode\n├── value: 1\n└── next: <cycle &CycleNode>", t)
+	check(ats.AnyToTree([]TagExample{{1, "p", "", 2, false}}, o), strings.Join([]string{
+		"[]TagExample",
+		"└── [0]",
+		"    ├── id: 1",
+		"    ├── Password: ***",
+		"    └── Plain: false",
+	}, "\n"), t)
+	check(ats.AnyToTree(struct{}{}, o), "{}", t)
+	check(ats.AnyToTree(5, o), "5", t)
+	check(ats.AnyToTree([]string{"a\nb", "c"}, o), strings.Join([]string{
+		"[]string",
+		"├── [0]: a",
+		"│   b",
+		"└── [1]: c",
+	}, "\n"), t)
+	check(ats.AnyToTree(map[string][]string{"k\ney": {"x\ny"}}, o), strings.Join([]string{
+		"map[string][]string",
+		"└── k",
+		"    ey",
+		"    └── [0]: x",
+		"        y",
+	}, "\n"), t)
+}
+
+func TestXML(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+	node := &CycleNode{1, nil}
+	node.next = node
+	a := XMLExample{1, `"a" & b`, []any{1, []int{2, 3}, nil}, "x<y", nil}
+
+	tests := []struct {
+		data     any
+		expected string
+	}{
+		{a, `<XMLExample id="1" name="&quot;a&quot; &amp; b">` +
+			"<Items>1</Items><Items><Items>2</Items><Items>3</Items></Items>" +
+			"<Items></Items><note>x&lt;y</note><Next></Next></XMLExample>"},
+		{[]int{1, 2}, "<value><int>1</int><int>2</int></value>"},
+		{[][]Example{{{1, "x", 'c'}}}, "<value><value><Example><a>1</a><B>x</B><c>99</c></Example></value></value>"},
+		{[]*Example{{1, "x", 'c'}}, "<value><Example><a>1</a><B>x</B><c>99</c></Example></value>"},
+		{map[string]int{"ok": 1, "bad key": 2},
+			`<value><entry key="bad key">2</entry><ok>1</ok></value>`},
+		{map[int]bool{1: true}, `<value><entry key="1">true</entry></value>`},
+		{&Example{1, "x", 'c'}, "<Example><a>1</a><B>x</B><c>99</c></Example>"},
+		{"a\tb\n\x01", "<string>a&#x9;b&#xA;\uFFFD</string>"},
+		{ExampleCustom{'a', 'b', 'c'}, "<ExampleCustom>a -&gt; b -&gt; c</ExampleCustom>"},
+		{TagExample{1, "p", "", 2, true},
+			"<TagExample><id>1</id><Password>***</Password><Plain>true</Plain></TagExample>"},
+		{node, "<CycleNode><value>1</value><next><!-- cycle &CycleNode --></next></CycleNode>"},
+		{nil, "<value></value>"},
+	}
+
+	for _, test := range tests {
+		actual := ats.AnyToXML(test.data, o)
+		check(actual, test.expected, t)
+		decoder := xml.NewDecoder(strings.NewReader(actual))
+		depth, roots := 0, 0
+
+		for {
+			token, err := decoder.Token()
+
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				t.fail(1, "invalid XML %s", err)
+				break
+			}
+
+			switch token.(type) {
+			case xml.StartElement:
+				if depth++; depth == 1 {
+					roots++
+				}
+			case xml.EndElement:
+				depth--
+			}
+		}
+
+		if roots != 1 {
+			t.fail(1, "expected a single root element, found %d", roots)
+		}
+	}
+
+	o.Pretty = true
+	check(ats.AnyToXML(XMLExample{2, "", []any{[]int{3}}, "", &a}, o), strings.Join([]string{
+		`<XMLExample id="2">`,
+		"    <Items>",
+		"        <Items>3</Items>",
+		"    </Items>",
+		"    <note></note>",
+		`    <Next id="1" name="&quot;a&quot; &amp; b">`,
+		"        <Items>1</Items>",
+		"        <Items>",
+		"            <Items>2</Items>",
+		"            <Items>3</Items>",
+		"        </Items>",
+		"        <Items></Items>",
+		"        <note>x&lt;y</note>",
+		"        <Next></Next>",
+		"    </Next>",
+		"</XMLExample>",
+	}, "\n"), t)
+	check(ats.AnyToXML(&[][]int{{1}, {}}, o), strings.Join([]string{
+		"<value>",
+		"    <value>",
+		"        <int>1</int>",
+		"    </value>",
+		"    <value></value>",
+		"</value>",
+	}, "\n"), t)
+}
+
+func TestYAML(ot *testing.T) {
+	t := newTester(ot)
+	o := ats.NewOptions()
+
+	check(ats.AnyToYAML([]int{1, 2}, o), "- 1\n- 2", t)
+	check(ats.AnyToYAML([][]int{{1}, {2, 3}}, o), "- - 1\n- - 2\n  - 3", t)
+	check(ats.AnyToYAML(map[int]bool{1: true}, o), "1: true", t)
+	check(ats.AnyToYAML([]any{nil, "", "true", "1.5", "a: b", "- x", math.Inf(1)}, o),
+		"- null\n- \"\"\n- \"true\"\n- \"1.5\"\n- \"a: b\"\n- \"- x\"\n- .inf", t)
+	check(ats.AnyToYAML(TagExample{1, "p", "", 2, true}, o), "id: 1\nPassword: \"***\"\nPlain: true", t)
+	check(ats.AnyToYAML([]any{struct{}{}, []int{}, map[string]int{}}, o), "- {}\n- []\n- {}", t)
+
+	data := map[string]any{
+		"list":  []Example{{1, "x\ny\n", 2}, {}},
+		"map":   map[string]int{"z": 1},
+		"multi": "a\nb",
+		"ptr":   &Example{B: "b"},
+	}
+	check(ats.AnyToYAML(data, o), strings.Join([]string{
+		"list:",
+		"  - a: 1",
+		"    B: |",
+		"      x",
+		"      y",
+		"    c: 2",
+		"  - a: 0",
+		`    B: ""`,
+		"    c: 0",
+		"map:",
+		"  z: 1",
+		"multi: |-",
+		"  a",
+		"  b",
+		"ptr:",
+		"  a: 0",
+		"  B: b",
+		"  c: 0",
+	}, "\n"), t)
+}
+
 func TestZero(ot *testing.T) {
 	t := newTester(ot)
 	check[interface{}](nil, "nil", t)
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"testing"
+
+	at "github.com/Matej-Chmel/go-any-to-string"
+)
+
+func TestGoSyntaxMain(t *testing.T) {
+	code, imports := at.AnyToGoSyntax([]Example{{a: 1, B: 2}}, at.NewOptions())
+	expected := "[]Example{{/* a: 1 */ B: 2}}"
+
+	if code != expected || len(imports) > 0 {
+		t.Errorf("\n%s %v\n\n!=\n\n%s []", code, imports, expected)
+	}
+}
@@ -1,9 +1,10 @@
 package internal
 
 import (
+	"errors"
 	r "reflect"
+	"strconv"
 	"strings"
-	"unsafe"
 
 	gs "github.com/Matej-Chmel/go-generic-stack"
 )
@@ -12,122 +13,377 @@ import (
 // In the end, writes all contents from builder to writer.
 type CompositeConverter struct {
 	builder strings.Builder
+	// Document tree that is rendered to fit LineWidth, nil if disabled
+	doc *document
+	// Graph of nodes written in DOT, nil if disabled
+	dot *dotGraph
+	// Errors of values that can't be written in the output format
+	errs []error
+	// Paths of packages referenced by the written Go syntax
+	imports map[string]bool
+	// Flag indicating whether a YAML block collection should start
+	// on the current line, which happens after a sequence dash
+	inlineBlock bool
+	// Labels of pointers referenced more than once, nil if disabled
+	labels *pointerLabels
 	LeafConverter
-	stack gs.Stack[*Item]
+	// Indentation level of lines started by the current Item in pretty mode
+	lineIndent int
+	// Indentation level of the next line in pretty mode
+	pendingIndent int
+	// Separator written before the next text unless a line is broken
+	pendingSep string
+	stack      gs.Stack[*Item]
+	// References of pointers, maps and slices held by Items on the stack
+	visited map[reference]bool
 }
 
 // Constructs new Converter with val as the first item in the stack
 func NewCompositeConverter(o *Options, val *r.Value) CompositeConverter {
+	o = newFormatOptions(o)
+
+	if o.SummaryHead < 0 || o.SummaryTail < 0 {
+		// Negative counts write no elements on their side of the elision
+		res := *o
+		res.SummaryHead = max(o.SummaryHead, 0)
+		res.SummaryTail = max(o.SummaryTail, 0)
+		o = &res
+	}
+
 	c := CompositeConverter{
 		builder:       strings.Builder{},
+		doc:           nil,
+		dot:           nil,
+		errs:          nil,
+		imports:       map[string]bool{},
+		inlineBlock:   false,
+		labels:        nil,
 		LeafConverter: NewLeafConverter(o),
 		stack:         gs.Stack[*Item]{},
+		visited:       map[reference]bool{},
+	}
+
+	if o.LineWidth > 0 && !o.Pretty {
+		c.doc = newDocument()
+	}
+
+	if o.format == formatDOT {
+		c.dot = newDOTGraph()
+	}
+
+	if o.PointerLabels {
+		c.labels = newPointerLabels(o, *val)
 	}
-	c.push(None, 0, val)
+
+	// The first block collection starts at the start of the document
+	c.inlineBlock = o.format == formatYAML
+
+	// The type of the first value isn't known from context
+	root := c.push(None, 0, val)
+	root.goContext = goInterface
+
+	if o.format == formatTOML {
+		// TOML document is a table
+		root.flag = TableValues
+	}
+
 	return c
 }
 
-// Converts arrays and slices. Items with 2 or 3 dimensions
-// are delegated to method convertArray2D3D.
+// Returns cells of a row of a 2D layer converted on their own
+// at given depth. The cells share references and labels with the layer.
+// Elided elements of a summarized row are replaced by a single cell.
+func (c *CompositeConverter) alignedCells(row r.Value, depth int) []tableCell {
+	if c.isString(&row) {
+		return []tableCell{{number: false, text: bytesToString(&row)}}
+	}
+
+	if ref, ok := newReference(&row); ok {
+		// Row is a part of the path to its cells
+		c.visited[ref] = true
+		defer delete(c.visited, ref)
+	}
+
+	// Each cell is written on a single line
+	o := *c.options
+	o.LineWidth = 0
+	o.PointerLabels = false
+	o.Pretty = false
+	length := row.Len()
+	summarized := c.isSummarized(length, o.MaxElements)
+	cells := []tableCell{}
+
+	for i := 0; i < length; i++ {
+		if summarized && i == o.SummaryHead {
+			cells = append(cells, tableCell{number: false, text: o.Elision})
+			i = length - o.SummaryTail
+		}
+
+		elem := row.Index(i)
+
+		if elem.Kind() == r.Interface && !elem.IsNil() {
+			elem = elem.Elem()
+		}
+
+		sub := NewCompositeConverter(&o, &elem)
+		sub.labels = c.labels
+		sub.stack.Top().depth = depth
+		sub.visited = c.visited
+		cells = append(cells, tableCell{
+			number: isMatrixElem(&o, elem.Type()),
+			text:   sub.ConvertStackToString(),
+		})
+	}
+
+	return cells
+}
+
+// Returns text written before a broken line break of a document.
+// Go syntax requires a comma at the end of each line of a literal,
+// JSON forbids it after the last element.
+func (c *CompositeConverter) brokenSep(last bool) string {
+	if c.options.format == formatGoSyntax || (c.options.format == formatJSON && !last) {
+		return ","
+	}
+
+	return ""
+}
+
+// Calls Error() string method of the Value. If ErrorChain is set,
+// the errors it wraps are written as well.
+// Returns false if the Value doesn't implement the error interface.
+func (c *CompositeConverter) callError(val *r.Value) (string, bool) {
+	receiver := c.findReceiver(val, "Error")
+
+	if !receiver.IsValid() {
+		return "", false
+	}
+
+	if c.options.ErrorChain && receiver.CanInterface() {
+		if err, ok := receiver.Interface().(error); ok {
+			return formatErrorChain(err, c.options), true
+		}
+	}
+
+	return callStringMethod(receiver.MethodByName("Error"))
+}
+
+// Calls GoString() string method of the Value.
+// Returns false if the Value doesn't implement the GoStringer interface.
+func (c *CompositeConverter) callGoString(val *r.Value) (string, bool) {
+	if receiver := c.findReceiver(val, "GoString"); receiver.IsValid() {
+		return callStringMethod(receiver.MethodByName("GoString"))
+	}
+
+	return "", false
+}
+
+// Calls String() string method of the Value.
+// Returns false if the Value doesn't implement the Stringer interface.
+func (c *CompositeConverter) callString(val *r.Value) (string, bool) {
+	if receiver := c.findReceiver(val, "String"); receiver.IsValid() {
+		return callStringMethod(receiver.MethodByName("String"))
+	}
+
+	return "", false
+}
+
+// Converts a 2D layer in two passes. All cells are converted first,
+// then each is padded to the widest cell of its column.
+func (c *CompositeConverter) convertAligned2D(it *Item) {
+	length := it.val.Len()
+	summarized := c.isSummarized(length, c.options.MaxElements)
+	var rows [][]tableCell
+	var rowValues []r.Value
+
+	for i := 0; i < length; i++ {
+		if summarized && i == c.options.SummaryHead {
+			// The elision takes place of a row
+			rows = append(rows, nil)
+			rowValues = append(rowValues, r.Value{})
+			i = length - c.options.SummaryTail
+		}
+
+		row := unwrapValue(it.val.Index(i))
+		rows = append(rows, c.alignedCells(row, it.depth+2))
+		rowValues = append(rowValues, row)
+	}
+
+	widths := []int{}
+
+	for _, row := range rows {
+		for i, cell := range row {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+
+			widths[i] = max(widths[i], textWidth(cell.text))
+		}
+	}
+
+	dims := it.GetOriginalDim()
+	layer := c.options.arrayDimension(dims, 2)
+	rowLayer := c.options.arrayDimension(dims, 1)
+	level := c.options.arrayLevel(dims, 2)
+	inner := level
+
+	if layer.Indent {
+		inner++
+	}
+
+	rowInner := inner
+
+	if rowLayer.Indent {
+		rowInner++
+	}
+
+	c.writeArrayToken(layer.Start, inner)
+
+	for i, row := range rows {
+		if i > 0 {
+			c.writeArrayToken(layer.Sep, inner)
+		}
+
+		c.writeArrayToken(rowLayer.Start, rowInner)
+
+		if row == nil {
+			c.write(c.options.Elision)
+		} else {
+			c.writeAlignedRow(row, widths, rowLayer.Sep)
+		}
+
+		if row != nil {
+			rowLength := rowValues[i].Len()
+			c.writeLength(&rowValues[i], c.isSummarized(rowLength, c.options.MaxElements))
+		}
+
+		c.writeArrayToken(rowLayer.End, inner)
+	}
+
+	c.writeLength(it.val, summarized)
+	c.writeArrayToken(layer.End, level)
+	c.pop()
+}
+
+// Converts arrays and slices. Layers of multidimensional arrays
+// are delegated to method convertArrayLayer.
 func (c *CompositeConverter) convertArray(it *Item) {
 	var currentDim uint32
 
 	if it.dim == 0 {
-		// Count dimensions for the most outer layer
-		currentDim = countDimensions(it.val)
+		// Count dimensions for the most outer layer,
+		// Go syntax and JSON write each layer as a separate array
+		if currentDim = 1; c.options.format != formatGoSyntax && c.options.format != formatJSON {
+			currentDim = countDimensions(it.val)
+		}
+
 		it.SetCurrentDim(currentDim)
 		it.SetOriginalDim(currentDim)
+
+		if currentDim >= 2 && c.stack.Len() > 1 {
+			// Nested multidimensional array starts on a new indented line
+			c.writeNestedArrayStart(it)
+		}
 	} else {
 		currentDim = it.GetCurrentDim()
 	}
 
-	// Delegate work for 2D and 3D
-	if currentDim >= 2 && currentDim <= 3 {
-		c.convertArray2D3D(it, currentDim)
+	if it.GetOriginalDim() > 1 {
+		c.convertArrayLayer(it, currentDim)
 		return
 	}
 
-	// Compute indentation
-	var indentLen int
-
-	if origDim := it.GetOriginalDim(); origDim <= 3 {
-		indentLen = 0
-	} else {
-		indentLen = int(origDim - max(currentDim, 3))
-	}
-
+	layer := c.options.arrayDimension(1, 1)
 	length := it.val.Len()
 
 	if it.ix == 0 {
-		// First item
-
-		c.writeIndent(indentLen)
+		// First item, write start
+		c.writeGoType(it)
+		c.writeGroupStart(layer.Start)
 
-		if currentDim >= 4 {
-			// 4D and higher, write start and newlines
-			c.write(c.options.ArrayStart)
-			c.write(c.options.ArraySep2D)
-		} else if currentDim == 1 && it.flag != InnerDim {
-			// 1D standalone, write start
-			c.write(c.options.ArrayStart)
+		if length > 0 {
+			c.writeGroupSep("", true)
 		}
-	} else if it.ix > 0 && it.ix < length {
-		// Items other than first one
+	} else if it.ix < length {
+		// Items other than first one, only 1 separator that may break the line
+		c.writeGroupSep(layer.Sep, false)
+	}
 
-		if currentDim >= 4 {
-			// 4D and higher, write separators
-			c.write(c.options.ArraySep2D)
-			c.writeIndent(indentLen)
-			c.write(c.options.ArrayEnd)
-			c.write(c.options.ArraySep2D)
-			c.writeIndent(indentLen)
-			c.write(c.options.ArrayStart)
-			c.write(c.options.ArraySep2D)
-		} else {
-			// 1D standalone or inner, only 1 separator
-			c.write(c.options.ArraySep)
-		}
-	} else if it.ix == length {
-		// End of the array
+	summarized := c.isSummarized(length, c.options.MaxElements)
+
+	if summarized && it.ix == c.options.SummaryHead {
+		// Skip the middle elements
+		c.write(c.options.Elision)
+		it.ix = length - c.options.SummaryTail
 
-		if currentDim >= 4 {
-			// 4D and higher, Write separator and end
-			c.write(c.options.ArraySep2D)
-			c.writeIndent(indentLen)
-			c.write(c.options.ArrayEnd)
-		} else if currentDim == 1 && it.flag != InnerDim {
-			// 1D standalone, write end
-			c.write(c.options.ArrayEnd)
+		if it.ix < length {
+			c.writeGroupSep(layer.Sep, false)
 		}
 	}
 
-	if it.ix == length {
-		// End of the array, pop item from stack
-		c.stack.Pop()
-	} else {
-		// Otherwise, push lower layer onto stack
+	if it.ix < length {
+		// Push the element onto stack
 		c.pushArrayItem(it, currentDim)
+		return
 	}
+
+	// End of the array
+	c.writeGroupEnd(layer.End, length == 0)
+	c.writeLength(it.val, summarized)
+
+	// Pop item from stack
+	c.pop()
 }
 
-// Converts a 2D and 3D arrays or slices
-func (c *CompositeConverter) convertArray2D3D(it *Item, currentDim uint32) {
-	if l := it.val.Len(); it.ix > 0 && it.ix < l {
+// Converts a layer of a multidimensional array or slice. Its elements
+// are separated by Sep of its dimension and enclosed in Start and End.
+// 2D layers with aligned columns are delegated to method convertAligned2D.
+func (c *CompositeConverter) convertArrayLayer(it *Item, currentDim uint32) {
+	if currentDim == 2 && c.options.AlignColumns && c.isAligned(it) {
+		c.convertAligned2D(it)
+		return
+	}
+
+	dims := it.GetOriginalDim()
+	layer := c.options.arrayDimension(dims, currentDim)
+	level := c.options.arrayLevel(dims, currentDim)
+	inner := level
+
+	if layer.Indent {
+		inner++
+	}
+
+	length := it.val.Len()
+
+	if it.ix == 0 {
+		c.writeArrayToken(layer.Start, inner)
+	} else if it.ix < length {
 		// Items other than first one
+		c.writeArrayToken(layer.Sep, inner)
+	}
 
-		if currentDim == 2 {
-			c.write(c.options.ArraySep2D)
-		} else {
-			c.write(c.options.ArraySep3D)
+	summarized := c.isSummarized(length, c.options.MaxElements)
+
+	if summarized && it.ix == c.options.SummaryHead {
+		// Skip the middle elements, the elision takes place of an element
+		c.writeArrayElision(dims, currentDim-1, inner)
+		it.ix = length - c.options.SummaryTail
+
+		if it.ix < length {
+			c.writeArrayToken(layer.Sep, inner)
 		}
-	} else if it.ix == l {
-		// End of the array, pop item from stack
-		c.stack.Pop()
+	}
+
+	if it.ix < length {
+		// Push lower layer onto stack
+		c.pushArrayItem(it, currentDim)
 		return
 	}
 
-	// Push lower layer onto stack
-	c.pushArrayItem(it, currentDim)
+	// End of the layer, the summary is written inside it
+	c.writeLength(it.val, summarized)
+	c.writeArrayToken(layer.End, level)
+	c.pop()
 }
 
 // Converts an array or slice of bytes as a string
@@ -135,7 +391,8 @@ func (c *CompositeConverter) convertBytes(it *Item) {
 	l := it.val.Len()
 
 	if it.ix == l {
-		c.stack.Pop()
+		c.writeLength(it.val, false)
+		c.pop()
 		return
 	}
 
@@ -151,6 +408,24 @@ func (c *CompositeConverter) convertBytes(it *Item) {
 func (c *CompositeConverter) convertComposites(it *Item, kind r.Kind) bool {
 	switch kind {
 	case r.Array, r.Slice:
+		switch c.options.format {
+		case formatDOT:
+			c.convertDOTArray(it)
+			return true
+		case formatTOML:
+			c.convertTOMLArray(it)
+			return true
+		case formatTree:
+			c.convertTreeArray(it)
+			return true
+		case formatXML:
+			c.convertXMLArray(it)
+			return true
+		case formatYAML:
+			c.convertYAMLArray(it)
+			return true
+		}
+
 		if it.flag == Bytes || it.flag == Runes {
 			// Item already flagged as string
 			c.convertBytes(it)
@@ -159,6 +434,26 @@ func (c *CompositeConverter) convertComposites(it *Item, kind r.Kind) bool {
 
 		if it.ix == 0 {
 			// Item is at the first stage of processing
+			if kind == r.Slice && c.convertCycle(it) {
+				return true
+			}
+
+			switch c.options.format {
+			case formatGoSyntax:
+				// Only slices can be written as strings in Go syntax
+				if !c.convertGoBytes(it) {
+					c.convertArray(it)
+				}
+
+				return true
+			case formatJSON:
+				if !c.convertJSONBytes(it) {
+					c.convertArray(it)
+				}
+
+				return true
+			}
+
 			// Kind of underlying element type
 			elemKind := it.val.Type().Elem().Kind()
 
@@ -192,33 +487,114 @@ func (c *CompositeConverter) convertComposites(it *Item, kind r.Kind) bool {
 	return true
 }
 
-// Attempts to write custom string representation of a struct
-// by finding and calling its String() string method
+// Attempts to write custom string representation of a value
+// by finding and calling its Error() string or String() string method.
+// The order of the methods is determined by ErrorBeforeString.
+// Go syntax is written by GoString() string method instead.
 func (c *CompositeConverter) convertCustomMethod(it *Item) bool {
 	if c.options.IgnoreCustomMethod {
 		return false
 	}
 
-	if method := it.val.MethodByName("String"); method.IsValid() {
-		res := method.Call(nil)
+	res, ok := "", false
 
-		if len(res) == 1 && res[0].Kind() == r.String {
-			c.write(res[0].String())
-			c.stack.Pop()
-			return true
+	if c.options.format == formatGoSyntax {
+		// Only GoString() string produces Go syntax
+		res, ok = c.callGoString(it.val)
+	} else if c.options.ErrorBeforeString {
+		if res, ok = c.callError(it.val); !ok {
+			res, ok = c.callString(it.val)
 		}
+	} else if res, ok = c.callString(it.val); !ok {
+		res, ok = c.callError(it.val)
+	}
+
+	if !ok {
+		return false
 	}
 
+	c.writeValue(it, c.formatScalar(res))
+	c.pop()
+	return true
+}
+
+// If the pointer, map or slice represented by Item it is already
+// being converted by an Item lower in the stack, writes a cycle marker
+// and returns true. Otherwise the reference is registered on Item it.
+func (c *CompositeConverter) convertCycle(it *Item) bool {
+	ref, ok := newReference(it.val)
+
+	if !ok {
+		return false
+	}
+
+	if c.visited[ref] {
+		marker := c.options.CycleStart + formatCycleType(it.val) + c.options.CycleEnd
+
+		if c.options.format == formatJSON || c.options.format == formatTOML {
+			// Type names may contain quotes
+			marker = jsonQuote(marker)
+		}
+
+		c.write(marker)
+		c.pop()
+		return true
+	}
+
+	c.registerReferences(it, []reference{ref})
 	return false
 }
 
+// If Item it is an interface whose dynamic value has been converted,
+// writes the dynamic type, pops the Item and returns true
+func (c *CompositeConverter) convertDynamicType(it *Item) bool {
+	if it.flag != DynamicType {
+		return false
+	}
+
+	elem := it.val.Elem()
+	c.write(c.options.DynamicTypeStart)
+	c.write(FormatType(&elem))
+	c.write(c.options.DynamicTypeEnd)
+	c.pop()
+	return true
+}
+
+// If Item it is a composite nested at least MaxDepth levels deep,
+// writes the elision symbol instead of its contents and returns true
+func (c *CompositeConverter) convertElided(it *Item, kind r.Kind) bool {
+	if c.options.MaxDepth <= 0 || it.depth < c.options.MaxDepth {
+		return false
+	}
+
+	switch kind {
+	case r.Array, r.Slice:
+		if c.isString(it.val) {
+			// Byte and rune arrays written as strings are kept
+			return false
+		}
+	case r.Map, r.Pointer, r.Struct:
+	default:
+		return false
+	}
+
+	if c.options.format == formatGoSyntax {
+		c.convertGoElided(it)
+		return true
+	}
+
+	c.write(c.options.Elision)
+	c.pop()
+	return true
+}
+
 // If Item it is a part of a byte or rune array, its value is written
 // as character and true is returned.
 func (c *CompositeConverter) convertFlaggedBytes(it *Item) bool {
 	if it.flag == Bytes {
-		c.write(c.formatByte(it.val))
+		c.writeValue(it, c.formatByte(it.val))
 	} else if it.flag == Runes {
-		c.write(c.formatRune(it.val))
+		c.writeValue(it, c.formatRune(it.val))
 	} else {
 		return false
 	}
@@ -226,11 +602,77 @@ func (c *CompositeConverter) convertFlaggedBytes(it *Item) bool {
 	return true
 }
 
+// If a formatter is registered for the type of Item it,
+// writes its result and returns true
+func (c *CompositeConverter) convertFormatter(it *Item) bool {
+	if it.flag == Bytes || it.flag == Runes || c.options.format == formatGoSyntax {
+		// Characters of a string aren't formatted separately
+		// and formatters don't produce Go syntax
+		return false
+	}
+
+	if f, ok := c.options.formatter(it.val.Type()); ok {
+		c.writeValue(it, c.formatScalar(f(*it.val)))
+		c.pop()
+		return true
+	}
+
+	return false
+}
+
+// If Item it is a non-nil interface, replaces it with its dynamic value
+// and returns true. If the dynamic type should be written, the dynamic
+// value is pushed as a new Item instead.
+func (c *CompositeConverter) convertInterface(it *Item) bool {
+	if it.val.Kind() != r.Interface {
+		return false
+	}
+
+	elem := it.val.Elem()
+
+	if !c.options.ShowDynamicType {
+		// The type of the dynamic value isn't known from context
+		it.goContext = goInterface
+		it.val = &elem
+		return true
+	}
+
+	// The dynamic value takes place of the interface
+	newItem := NewItem(it.flag, 0, &elem)
+	newItem.depth = it.depth
+	newItem.indent = it.indent
+	c.stack.Push(newItem)
+	it.flag = DynamicType
+	return true
+}
+
 // Determines kind of Item it and writes its value into
 // a builder. Item may be popped from the stack if fully processed.
 func (c *CompositeConverter) convertItem(it *Item) {
+	c.lineIndent = it.indent
+
+	// Attempt to finish an unwrapped interface
+	if c.convertDynamicType(it) {
+		return
+	}
+
+	// Attempt to convert a TOML table
+	if c.convertTOMLFlagged(it) {
+		return
+	}
+
 	// Attempt to convert a nil pointer
-	if c.convertNil(it.val) {
+	if c.convertNil(it) {
+		return
+	}
+
+	// Attempt to use a registered formatter
+	if c.convertFormatter(it) {
+		return
+	}
+
+	// Attempt to unwrap an interface
+	if c.convertInterface(it) {
 		return
 	}
 
@@ -241,25 +683,65 @@ func (c *CompositeConverter) convertItem(it *Item) {
 
 	kind := it.val.Kind()
 
+	if c.convertElided(it, kind) {
+		return
+	}
+
 	if c.convertComposites(it, kind) {
 		return
 	}
 
+	switch c.options.format {
+	case formatDOT:
+		c.write(dotEscape(c.ConvertToString(it.val)))
+		c.pop()
+		return
+	case formatGoSyntax:
+		// The value is written before the suffix of the Item
+		c.convertGoLeaf(it)
+		c.pop()
+		return
+	case formatJSON:
+		c.convertJSONLeaf(it)
+		c.pop()
+		return
+	case formatTOML:
+		c.convertTOMLLeaf(it)
+		c.pop()
+		return
+	case formatXML:
+		// The value is written before the end tag of its element
+		c.write(xmlEscape(c.ConvertToString(it.val)))
+		c.pop()
+		return
+	case formatYAML:
+		c.convertYAMLLeaf(it)
+		c.pop()
+		return
+	}
+
 	// Item is not a composite, one pass will suffice,
 	// pop the item from the stack
-	c.stack.Pop()
+	c.pop()
 
 	if c.convertFlaggedBytes(it) {
 		return
 	}
 
-	c.write(c.ConvertToString(it.val))
+	c.writeValue(it, c.ConvertToString(it.val))
 }
 
 // Converts a map
 func (c *CompositeConverter) convertMap(it *Item) {
+	length := it.val.Len()
+
 	if it.flag == None && it.ix == 0 {
-		// First stage, save keys so that order doesn't change
+		// First stage, stop if the map contains itself
+		if c.convertCycle(it) {
+			return
+		}
+
+		// Save keys so that order doesn't change
 		it.flag = KeyNext
 		it.keys = it.val.MapKeys()
 
@@ -267,48 +749,124 @@ func (c *CompositeConverter) convertMap(it *Item) {
 			SortKeys(it.keys, c.options.GetLessFunc)
 		}
 
-		c.write(c.options.MapStart)
-	} else if it.flag == KeyNext && it.ix < it.val.Len() {
-		// Write separator between two key-value pairs
-		c.write(c.options.MapSepVal)
-	} else if it.ix == it.val.Len() {
-		// End of map, pop item from the stack
-		c.write(c.options.MapEnd)
-		c.stack.Pop()
-		return
+		c.writeGoType(it)
+		c.writeGroupStart(c.options.MapStart)
 	}
 
-	if key := it.keys[it.ix]; it.flag == KeyNext {
-		// Convert a key next
-		c.push(None, 0, &key)
-		it.flag = ValueNext
-	} else if it.flag == ValueNext {
+	if it.flag == ValueNext {
 		// Write separator between key and value
-		c.write(c.options.MapSepKey)
+		c.writeKeySep(c.options.MapSepKey)
 
 		// Find and convert a value next
-		val := it.val.MapIndex(key)
-		c.push(None, 0, &val)
+		val := it.val.MapIndex(it.keys[it.ix])
+		c.pushEntry(it, &val).goContext = goElided
 		it.flag = KeyNext
 
 		// Move index onto the next key-value pair
 		it.ix++
+		return
+	}
+
+	summarized := c.isSummarized(length, c.options.MaxEntries)
+
+	if summarized && it.ix == c.options.SummaryHead {
+		// Skip the middle key-value pairs
+		c.writeEntrySep(it, c.options.MapSepVal)
+		c.write(c.options.Elision)
+		it.count++
+		it.ix = length - c.options.SummaryTail
+	}
+
+	if it.ix == length {
+		// End of map, pop item from the stack
+		c.writeEntriesEnd(it, c.options.MapEnd)
+		c.writeLength(it.val, summarized)
+
+		c.pop()
+		return
 	}
+
+	if c.options.format == formatTOML {
+		// Skip values that can't be written
+		val := it.val.MapIndex(it.keys[it.ix])
+
+		if c.tomlSkipped(it, c.formatTOMLKey(&it.keys[it.ix]), &val) {
+			it.ix++
+			return
+		}
+	}
+
+	switch c.options.format {
+	case formatDOT:
+		val := it.val.MapIndex(it.keys[it.ix])
+		c.convertDOTEntry(it, c.formatDOTKey(&it.keys[it.ix]), false, &val)
+		it.ix++
+		return
+	case formatTree:
+		val := it.val.MapIndex(it.keys[it.ix])
+		label := c.formatTreeKey(&it.keys[it.ix])
+		c.pushTreeEntry(it, label, it.ix == length-1, &val)
+		it.count++
+		it.ix++
+		return
+	case formatXML:
+		// Values are written as elements named by their keys
+		val := it.val.MapIndex(it.keys[it.ix])
+		c.convertXMLEntry(it, c.formatXMLKey(&it.keys[it.ix]), false, &val)
+		it.ix++
+		return
+	}
+
+	// Convert a key next
+	c.writeEntrySep(it, c.options.MapSepVal)
+
+	switch c.options.format {
+	case formatJSON, formatTOML, formatYAML:
+		// Keys are written as strings or scalars
+		c.write(c.formatMapKey(&it.keys[it.ix]))
+		it.count++
+		it.flag = ValueNext
+		return
+	}
+
+	key := c.pushEntry(it, &it.keys[it.ix])
+	key.goContext = goElided
+	key.suffix = ""
+	it.count++
+	it.flag = ValueNext
 }
 
-// If Item represents a nil pointer, writes "nil" and returns true
-func (c *CompositeConverter) convertNil(val *r.Value) bool {
-	if IsNil(val) {
+// If Item represents a nil pointer, writes "nil" and returns true.
+// Its element is left empty in XML.
+func (c *CompositeConverter) convertNil(it *Item) bool {
+	if !IsNil(it.val) {
+		return false
+	}
+
+	switch c.options.format {
+	case formatGoSyntax:
+		c.write(c.formatGoNil(it))
+	case formatJSON, formatYAML:
+		c.write("null")
+	case formatTOML:
+		// TOML has no null, other nil values are skipped
+		c.write(c.formatTOMLNil(it.val))
+	case formatXML:
+	default:
 		c.write("nil")
-		c.stack.Pop()
-		return true
 	}
 
-	return false
+	c.pop()
+	return true
 }
 
 // Converts a pointer
 func (c *CompositeConverter) convertPointer(it *Item) {
+	// Labels take place of cycle markers
+	if c.convertLabel(it) || c.convertCycle(it) {
+		return
+	}
+
 	elem := it.val.Elem()
 
 	if elem.Kind() == r.Struct {
@@ -322,8 +880,29 @@ func (c *CompositeConverter) convertPointer(it *Item) {
 		}
 	}
 
-	c.write("&")
-	it.val = &elem
+	switch c.options.format {
+	case formatGoSyntax:
+		c.convertGoPointer(it, &elem)
+	case formatJSON, formatTOML, formatTree, formatXML, formatYAML:
+		// JSON, TOML, XML and YAML have no pointers,
+		// the target is written in place, so is an entry of a tree
+		it.val = &elem
+	default:
+		if c.options.PointerAddresses {
+			c.write(formatAddress(it.val) + c.options.LabelSep)
+		}
+
+		c.write("&")
+		it.val = &elem
+	}
+
+	// The target of the pointer is one level deeper
+	it.depth++
+}
+
+// Returns errors of values that couldn't be written in the output format
+func (c *CompositeConverter) Err() error {
+	return errors.Join(c.errs...)
 }
 
 // Run the whole conversion from start to finish
@@ -335,10 +914,28 @@ func (c *CompositeConverter) ConvertStackToString() string {
 		c.writeRune(' ')
 	}
 
-	for c.stack.HasItems() {
+	switch c.options.format {
+	case formatDOT:
+		c.startDOTGraph(firstItem)
+	case formatTree:
+		c.writeTreeRoot(firstItem)
+	case formatXML:
+		c.startXMLRoot(firstItem)
+	}
+
+	// Nodes of a graph are converted one after another
+	for c.stack.HasItems() || c.pushDOTNode() {
 		c.convertItem(c.stack.Top())
 	}
 
+	if c.dot != nil {
+		c.writeDOTEdges()
+	}
+
+	if c.doc != nil {
+		c.doc.render(&c.builder, c.options.LineWidth, c.options.PrettyIndent)
+	}
+
 	return c.builder.String()
 }
 
@@ -355,42 +952,261 @@ func (c *CompositeConverter) convertStruct(it *Item) {
 	}
 
 	if it.ix == 0 {
-		if c.options.ShowFieldNames {
-			it.typ = it.val.Type()
+		it.typ = it.val.Type()
+		c.writeGoType(it)
+		c.writeGroupStart(c.options.StructStart)
+
+		if c.options.format == formatXML {
+			c.writeXMLAttributes(it)
 		}
+	}
+
+	// Skip fields that shouldn't be written according to their tags
+	var field r.Value
+	var tag fieldTag
+	numField := it.val.NumField()
 
-		c.write(c.options.StructStart)
-	} else if it.ix < it.val.NumField() {
-		c.write(c.options.StructSepFieldValue)
-	} else if it.ix == it.val.NumField() {
+	for ; it.ix < numField; it.ix++ {
+		tag = newFieldTag(it.typ.Field(it.ix), c.options.TagName)
+		field = structField(it.val, it.ix)
+
+		if !tag.omit && !(tag.omitEmpty && field.IsZero()) &&
+			!c.tomlSkipped(it, tag.name, &field) && !c.isXMLAttribute(tag) {
+			break
+		}
+	}
+
+	if it.ix == numField {
 		// End of struct, pop item from the stack
-		c.write(c.options.StructEnd)
-		c.stack.Pop()
+		c.writeEntriesEnd(it, c.options.StructEnd)
+		c.pop()
+		return
+	}
+
+	if c.options.format == formatGoSyntax && !c.options.GoSyntaxUnexported &&
+		!it.typ.Field(it.ix).IsExported() {
+		// Unexported fields can't be set outside of their package
+		c.convertGoUnexported(it, tag.name, &field)
+		it.ix++
 		return
 	}
 
+	switch c.options.format {
+	case formatDOT:
+		c.convertDOTEntry(it, tag.name, tag.redact, &field)
+		it.ix++
+		return
+	case formatTree:
+		if tag.redact {
+			c.writeTreeLine(it, tag.name, c.isLastField(it))
+			c.write(": " + c.options.RedactedValue)
+		} else {
+			c.pushTreeEntry(it, tag.name, c.isLastField(it), &field)
+		}
+
+		it.count++
+		it.ix++
+		return
+	case formatXML:
+		c.convertXMLEntry(it, xmlEntryTag(xmlEscape(tag.name)), tag.redact, &field)
+		it.ix++
+		return
+	}
+
+	if c.options.format == formatGoSyntax && !c.options.Pretty && it.count == 0 && it.ix > 0 {
+		// Separate the first field from comments before it
+		c.write(" ")
+	}
+
+	c.writeEntrySep(it, c.options.StructSepFieldValue)
+
 	if c.options.ShowFieldNames {
-		c.write(it.typ.Field(it.ix).Name)
-		c.write(c.options.StructSepFieldName)
+		c.write(c.formatFieldName(tag.name))
+		c.writeKeySep(c.options.StructSepFieldName)
 	}
 
-	if field := it.val.Field(it.ix); field.CanInterface() {
-		// Current field is exported, simply process it
-		c.push(None, 0, &field)
+	if tag.redact {
+		c.write(c.options.RedactedValue)
 	} else {
-		// Current field is unexported, retrieve its value from a memory address
-		addr := unsafe.Pointer(field.UnsafeAddr())
-		data := r.NewAt(field.Type(), addr).Elem()
-		c.push(None, 0, &data)
+		c.pushEntry(it, &field)
 	}
 
 	// Move index onto the next field
+	it.count++
 	it.ix++
 }
 
-// Push new Item onto the stack
-func (c *CompositeConverter) push(flag uint, index int, val *r.Value) {
-	c.stack.Push(NewItem(flag, index, val))
+// Returns a receiver that has the method of given name. If PointerMethods
+// is set and the method is declared on a pointer receiver, an addressable
+// copy is returned. Returns an invalid Value if there is no such method.
+func (c *CompositeConverter) findReceiver(val *r.Value, name string) r.Value {
+	if val.MethodByName(name).IsValid() {
+		return *val
+	}
+
+	if kind := val.Kind(); !c.options.PointerMethods ||
+		kind == r.Interface || kind == r.Pointer {
+		return r.Value{}
+	}
+
+	var ptr r.Value
+
+	if val.CanAddr() {
+		ptr = val.Addr()
+	} else {
+		// Create pointer to a copy like convertStruct does
+		ptr = r.New(val.Type())
+		ptr.Elem().Set(*val)
+	}
+
+	if ptr.MethodByName(name).IsValid() {
+		return ptr
+	}
+
+	return r.Value{}
+}
+
+// Returns the name of a struct field as a key
+// in the syntax of the output format
+func (c *CompositeConverter) formatFieldName(name string) string {
+	switch c.options.format {
+	case formatJSON:
+		return jsonQuote(name)
+	case formatTOML:
+		return tomlKey(name)
+	case formatYAML:
+		return yamlQuote(name)
+	}
+
+	return name
+}
+
+// Returns a key of a map as a string or scalar
+// in the syntax of the output format
+func (c *CompositeConverter) formatMapKey(key *r.Value) string {
+	switch c.options.format {
+	case formatTOML:
+		return c.formatTOMLKey(key)
+	case formatYAML:
+		return c.formatYAMLKey(key)
+	}
+
+	return c.formatJSONKey(key)
+}
+
+// Returns a string written by a custom method or a formatter
+// as a string in JSON and TOML, text in XML or a scalar in YAML.
+// Special characters of records are escaped in DOT.
+func (c *CompositeConverter) formatScalar(s string) string {
+	switch c.options.format {
+	case formatDOT:
+		return dotEscape(s)
+	case formatJSON, formatTOML:
+		return jsonQuote(s)
+	case formatXML:
+		return xmlEscape(s)
+	case formatYAML:
+		return yamlQuote(s)
+	}
+
+	return s
+}
+
+// Returns true if columns of the 2D layer represented by Item it
+// can be aligned. Layers whose rows are elided by MaxDepth
+// or contain a cycle are written row by row.
+func (c *CompositeConverter) isAligned(it *Item) bool {
+	if c.options.MaxDepth > 0 && it.depth+1 >= c.options.MaxDepth {
+		return false
+	}
+
+	for i := 0; i < it.val.Len(); i++ {
+		row := it.val.Index(i)
+
+		if ref, ok := newReference(&row); ok && c.visited[ref] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns true if a collection of given length exceeds limit
+// and only its first and last elements should be written
+func (c *CompositeConverter) isSummarized(length, limit int) bool {
+	return limit > 0 && length > limit &&
+		c.options.SummaryHead+c.options.SummaryTail < length
+}
+
+// Returns true if an array or slice should be written as a string
+func (c *CompositeConverter) isString(val *r.Value) bool {
+	switch val.Type().Elem().Kind() {
+	case r.Int32:
+		return c.options.RuneAsString
+	case r.Uint8:
+		return c.options.ByteAsString
+	}
+
+	return false
+}
+
+// Pops the top Item from the stack, writes its suffix,
+// releases its references and closes its document nest
+func (c *CompositeConverter) pop() {
+	top := c.stack.Top()
+
+	for _, ref := range top.refs {
+		delete(c.visited, ref)
+	}
+
+	c.write(top.suffix)
+
+	if top.nested {
+		c.doc.close()
+	}
+
+	c.stack.Pop()
+}
+
+// Registers references on Item it so that they are released when it's popped
+func (c *CompositeConverter) registerReferences(it *Item, refs []reference) {
+	for _, ref := range refs {
+		c.visited[ref] = true
+		it.refs = append(it.refs, ref)
+	}
+}
+
+// Push new Item onto the stack one level deeper than the top Item
+func (c *CompositeConverter) push(flag uint, index int, val *r.Value) *Item {
+	newItem := NewItem(flag, index, val)
+
+	if c.stack.HasItems() {
+		top := c.stack.Top()
+		newItem.depth = top.depth + 1
+		newItem.indent = top.indent
+		newItem.key = top.key
+		newItem.path = top.path
+	}
+
+	c.stack.Push(newItem)
+	return newItem
+}
+
+// Push a field of a struct or a key or value of a map represented
+// by Item it onto the stack. In pretty mode, it's indented one level deeper
+// and Go syntax ends it with a comma.
+func (c *CompositeConverter) pushEntry(it *Item, val *r.Value) *Item {
+	newItem := c.push(None, 0, val)
+
+	if c.options.Pretty || c.options.format == formatYAML {
+		newItem.indent = it.indent + 1
+
+		if c.options.format == formatGoSyntax {
+			newItem.suffix = ","
+		}
+	}
+
+	return newItem
 }
 
 // Push the next element from array or slice represented by the Item it
@@ -398,9 +1214,20 @@ func (c *CompositeConverter) push(flag uint, index int, val *r.Value) {
 func (c *CompositeConverter) pushArrayItem(it *Item, currentDim uint32) {
 	elem := it.val.Index(it.ix)
 
+	if currentDim <= 1 {
+		// Elements of the last layer are converted on their own,
+		// so that maps start with their keys rather than as an inner
+		// layer. Their type is elided in Go syntax.
+		c.push(None, 0, &elem).goContext = goElided
+		it.ix++
+		return
+	}
+
 	// The next layer is an inner dimension
 	newItem := NewItem(InnerDim, 0, &elem)
+	newItem.depth = it.depth + 1
 	newItem.dim = it.dim
+	newItem.indent = it.indent
 	newItem.SetCurrentDim(currentDim - 1)
 	c.stack.Push(newItem)
 
@@ -408,24 +1235,308 @@ func (c *CompositeConverter) pushArrayItem(it *Item, currentDim uint32) {
 	it.ix++
 }
 
-// Write a string to builder
+// Write a string to builder. In pretty mode, lines that follow
+// a line break are indented.
 func (c *CompositeConverter) write(s string) {
-	c.builder.WriteString(s)
+	if c.doc != nil {
+		c.doc.text(s)
+		return
+	}
+
+	if s == "" {
+		return
+	}
+
+	if sep := c.pendingSep; sep != "" {
+		c.pendingSep = ""
+		c.write(sep)
+	}
+
+	c.inlineBlock = false
+
+	if !c.options.Pretty && c.options.format != formatYAML {
+		c.builder.WriteString(s)
+		return
+	}
+
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			c.builder.WriteByte('\n')
+			c.pendingIndent = c.lineIndent
+		}
+
+		if line != "" {
+			c.writePendingIndent()
+			c.builder.WriteString(line)
+		}
+	}
+}
+
+// Write cells of a row separated by sep and padded to widths
+// of their columns. Numbers are aligned to the right, the last cell
+// of other values isn't padded.
+func (c *CompositeConverter) writeAlignedRow(cells []tableCell, widths []int, sep string) {
+	for i, cell := range cells {
+		if i > 0 {
+			c.write(sep)
+		}
+
+		padding := strings.Repeat(" ", widths[i]-textWidth(cell.text))
+
+		if cell.number {
+			c.write(padding + cell.text)
+		} else if i < len(cells)-1 {
+			c.write(cell.text + padding)
+		} else {
+			c.write(cell.text)
+		}
+	}
+}
+
+// Write the elision of a summarized layer enclosed in symbols
+// of dimension d of its elements
+func (c *CompositeConverter) writeArrayElision(dims, d uint32, level int) {
+	if d == 0 {
+		c.write(c.options.Elision)
+		return
+	}
+
+	layer := c.options.arrayDimension(dims, d)
+	inner := level
+
+	if layer.Indent {
+		inner++
+	}
+
+	c.writeArrayToken(layer.Start, inner)
+	c.write(c.options.Elision)
+	c.writeArrayToken(layer.End, level)
+}
+
+// Write a symbol of a layer of a multidimensional array. Lines that follow
+// its line breaks are indented by level, empty lines aren't indented.
+func (c *CompositeConverter) writeArrayToken(token string, level int) {
+	lines := strings.Split(token, "\n")
+
+	for i, line := range lines {
+		if i > 0 {
+			c.write("\n")
+
+			if line != "" || i == len(lines)-1 {
+				c.writeIndent(level)
+			}
+		}
+
+		c.write(line)
+	}
 }
 
 // Write a byte to builder
 func (c *CompositeConverter) writeByte(b byte) {
-	c.builder.WriteByte(b)
+	c.write(string(b))
 }
 
-// Write indentation to builder
+// Write the end of a struct or map. In pretty mode, the end symbol
+// is placed on its own line if the Item it isn't empty.
+// YAML block collections have no end, but empty ones are written in flow style.
+// XML elements are ended by their end tags, DOT records by their nodes.
+// Trees have no end, but empty structs and maps are written as braces.
+func (c *CompositeConverter) writeEntriesEnd(it *Item, end string) {
+	switch c.options.format {
+	case formatDOT:
+		// Records are ended by their nodes
+		return
+	case formatTree, formatYAML:
+		if it.count == 0 {
+			c.write("{}")
+		}
+
+		return
+	case formatXML:
+		if it.count > 0 {
+			c.writeXMLLine(it.indent)
+		}
+
+		return
+	}
+
+	if c.options.Pretty && it.count > 0 {
+		c.writeLine(it.indent)
+	}
+
+	c.writeGroupEnd(end, it.count == 0)
+}
+
+// Write separator before a field of a struct or a key-value pair of a map.
+// In pretty mode, each one starts on a new line.
+func (c *CompositeConverter) writeEntrySep(it *Item, sep string) {
+	if c.options.format == formatYAML {
+		c.writeYAMLLine(it.indent)
+	} else if c.options.Pretty {
+		if c.options.format == formatJSON && it.count > 0 {
+			c.write(c.brokenSep(false))
+		}
+
+		c.writeLine(it.indent + 1)
+	} else {
+		c.writeGroupSep(sep, it.count == 0)
+	}
+}
+
+// Write the end of a struct, map or 1D array. If a document is built,
+// closes the group opened by writeGroupStart.
+func (c *CompositeConverter) writeGroupEnd(end string, empty bool) {
+	if c.doc == nil {
+		c.write(end)
+		return
+	}
+
+	c.doc.close()
+
+	if !empty {
+		c.doc.line("", c.brokenSep(true))
+	}
+
+	c.doc.text(end)
+	c.doc.close()
+}
+
+// Write separator before an element of a struct, map or 1D array.
+// If a document is built, the separator is a line break
+// that is broken if the group doesn't fit on the line.
+func (c *CompositeConverter) writeGroupSep(sep string, first bool) {
+	if c.doc != nil {
+		if first {
+			c.doc.line("", "")
+		} else {
+			c.doc.line(sep, c.brokenSep(false))
+		}
+	} else if !first {
+		c.write(sep)
+	}
+}
+
+// Write the start of a struct, map or 1D array. If a document is built,
+// opens a group whose contents are nested one level deeper.
+func (c *CompositeConverter) writeGroupStart(start string) {
+	if c.doc == nil {
+		c.write(start)
+		return
+	}
+
+	c.doc.openNode(docGroup)
+	c.doc.text(start)
+	c.doc.openNode(docNest)
+}
+
+// Write a line break before a multidimensional array nested in another
+// value in pretty mode or if a document is built
+func (c *CompositeConverter) writeNestedArrayStart(it *Item) {
+	if c.doc != nil {
+		// The nest is closed when the Item is popped
+		c.doc.openNode(docNest)
+		c.doc.line("", "")
+		it.nested = true
+	} else if c.options.Pretty {
+		it.indent++
+		c.lineIndent = it.indent
+
+		if strings.HasSuffix(c.builder.String(), "\n") {
+			// Line of a field without a name is still empty
+			c.pendingIndent = it.indent
+		} else {
+			c.writeLine(it.indent)
+		}
+	}
+}
+
+// Write indentation to builder. Pretty mode and documents indent
+// by PrettyIndent, so that lines of arrays nested in structs and maps
+// are indented by a single symbol.
 func (c *CompositeConverter) writeIndent(length int) {
+	indent := c.options.ArrayIndent
+
+	if c.options.Pretty || c.doc != nil {
+		indent = c.options.PrettyIndent
+	}
+
 	for i := 0; i < length; i++ {
-		c.write(c.options.ArrayIndent)
+		c.write(indent)
+	}
+}
+
+// Write a separator between a key and a value.
+// In YAML, the value is separated by a space unless it's a block collection.
+func (c *CompositeConverter) writeKeySep(sep string) {
+	c.write(sep)
+
+	if c.options.format == formatYAML {
+		c.pendingSep = " "
+	}
+}
+
+// Write the length of a collection. If ShowLen is set, it's written
+// as an annotation, otherwise only summarized collections have it.
+func (c *CompositeConverter) writeLength(val *r.Value, summarized bool) {
+	if c.options.ShowLen {
+		c.write(c.formatLength(val))
+	} else if summarized {
+		c.writeSummary(val.Len())
 	}
 }
 
+// Write a line break, the next line is indented by level
+func (c *CompositeConverter) writeLine(level int) {
+	c.builder.WriteByte('\n')
+	c.pendingIndent = level
+}
+
+// Write indentation of a line that has just started
+func (c *CompositeConverter) writePendingIndent() {
+	for i := 0; i < c.pendingIndent; i++ {
+		c.builder.WriteString(c.options.PrettyIndent)
+	}
+
+	c.pendingIndent = 0
+}
+
+// Write the length of a summarized collection
+func (c *CompositeConverter) writeSummary(length int) {
+	c.write(c.options.SummaryStart)
+	c.write(strconv.Itoa(length))
+	c.write(c.options.SummaryEnd)
+}
+
 // Write a rune to builder
 func (c *CompositeConverter) writeRune(r rune) {
-	c.builder.WriteRune(r)
+	c.write(string(r))
+}
+
+// Write text of a value of Item it. Line breaks of the value are kept
+// as they are, lines that follow them aren't indented.
+// In a tree, they are prefixed by connectors of enclosing entries.
+func (c *CompositeConverter) writeValue(it *Item, s string) {
+	if c.options.format == formatTree {
+		c.write(strings.ReplaceAll(s, "\n", "\n"+it.prefix))
+		return
+	}
+
+	if c.doc != nil {
+		c.doc.raw(s)
+		return
+	}
+
+	if !c.options.Pretty {
+		c.write(s)
+		return
+	}
+
+	for i, line := range strings.Split(s, "\n") {
+		if i > 0 {
+			c.write("\n")
+			c.pendingIndent = 0
+		}
+
+		c.write(line)
+	}
 }
@@ -0,0 +1,198 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	r "reflect"
+	"strings"
+)
+
+// Converts an array or slice of structs or maps to CSV with a header row.
+// If no columns are given, they're named by fields of the element type
+// and by fields and keys of the rows in order of their first appearance. Cells are written like values nested
+// in a struct, missing ones are empty.
+func ConvertToCSV(o *Options, val *r.Value, columns []string) (string, error) {
+	elem := unwrapValue(*val)
+	rows, err := csvRows(elem)
+
+	if err != nil {
+		return "", err
+	}
+
+	cellOptions := newCellOptions(o)
+	names := make([][]string, len(rows))
+	cells := make([]map[string]string, len(rows))
+
+	for i, row := range rows {
+		names[i], cells[i] = csvCells(cellOptions, row)
+	}
+
+	if len(columns) == 0 {
+		// Fields of the element type name columns even if there are no rows
+		names = append([][]string{csvFieldNames(cellOptions, elem.Type().Elem())}, names...)
+		columns = csvColumns(names)
+	}
+
+	records := make([][]string, 0, len(rows)+1)
+	records = append(records, columns)
+
+	for _, rowCells := range cells {
+		record := make([]string, len(columns))
+
+		for i, column := range columns {
+			record[i] = rowCells[column]
+		}
+
+		records = append(records, record)
+	}
+
+	var builder strings.Builder
+
+	if err := csv.NewWriter(&builder).WriteAll(records); err != nil {
+		return "", err
+	}
+
+	return builder.String(), nil
+}
+
+// Returns names of columns and cells of a row. Fields of a struct
+// are named by their tags, keys of a map are converted on their own.
+func csvCells(o *Options, row r.Value) ([]string, map[string]string) {
+	names := []string{}
+	cells := map[string]string{}
+
+	switch row.Kind() {
+	case r.Map:
+		keys := row.MapKeys()
+
+		if o.GetLessFunc != nil {
+			SortKeys(keys, o.GetLessFunc)
+		}
+
+		for _, key := range keys {
+			name := formatCSVCell(o, key)
+			names = append(names, name)
+			cells[name] = formatCSVCell(o, row.MapIndex(key))
+		}
+	case r.Struct:
+		for i := 0; i < row.NumField(); i++ {
+			tag := newFieldTag(row.Type().Field(i), o.TagName)
+
+			if tag.omit {
+				continue
+			}
+
+			field := structField(&row, i)
+			names = append(names, tag.name)
+
+			if tag.redact {
+				cells[tag.name] = o.RedactedValue
+			} else if !tag.omitEmpty || !field.IsZero() {
+				cells[tag.name] = formatCSVCell(o, field)
+			}
+		}
+	}
+
+	return names, cells
+}
+
+// Returns names of columns of all rows in order of their first appearance
+func csvColumns(names [][]string) []string {
+	columns := []string{}
+	seen := map[string]bool{}
+
+	for _, rowNames := range names {
+		for _, name := range rowNames {
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+
+	return columns
+}
+
+// Returns names of fields of a struct type or a pointer to it,
+// other types have no fields
+func csvFieldNames(o *Options, aType r.Type) []string {
+	for aType.Kind() == r.Pointer {
+		aType = aType.Elem()
+	}
+
+	if aType.Kind() != r.Struct {
+		return nil
+	}
+
+	names := []string{}
+
+	for i := 0; i < aType.NumField(); i++ {
+		if tag := newFieldTag(aType.Field(i), o.TagName); !tag.omit {
+			names = append(names, tag.name)
+		}
+	}
+
+	return names
+}
+
+// Returns elements of an array or slice with interfaces and pointers
+// unwrapped. Structs are made addressable so that unexported fields
+// can be read. Returns an error if an element isn't a struct or map.
+func csvRows(val r.Value) ([]r.Value, error) {
+	if kind := val.Kind(); kind != r.Array && kind != r.Slice {
+		return nil, fmt.Errorf(
+			"CSV table must be an array or slice, not %s", csvTypeName(&val))
+	}
+
+	rows := make([]r.Value, val.Len())
+
+	for i := range rows {
+		row := unwrapValue(val.Index(i))
+
+		switch row.Kind() {
+		case r.Interface, r.Map, r.Pointer:
+			// Nil elements are written as empty rows
+		case r.Struct:
+			// Create pointer to the struct like convertStruct does
+			tmp := r.New(row.Type())
+			tmp.Elem().Set(row)
+			row = tmp.Elem()
+		default:
+			return nil, fmt.Errorf(
+				"CSV row %d must be a struct or map, not %s", i, csvTypeName(&row))
+		}
+
+		rows[i] = row
+	}
+
+	return rows, nil
+}
+
+// Returns the type of a value in an error message
+func csvTypeName(val *r.Value) string {
+	if !val.IsValid() {
+		return "nil"
+	}
+
+	return FormatType(val)
+}
+
+// Returns a value converted on its own
+func formatCSVCell(o *Options, val r.Value) string {
+	c := NewCompositeConverter(o, &val)
+	return c.ConvertStackToString()
+}
+
+// Constructs options of cells of a table. Each cell is written
+// on a single line, options that annotate or lay out a whole value
+// are turned off.
+func newCellOptions(o *Options) *Options {
+	res := *o
+	res.LineWidth = 0
+	res.Markdown = false
+	res.Pretty = false
+	res.ShowLen = false
+	res.ShowType = false
+	res.Table = false
+	return &res
+}
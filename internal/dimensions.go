@@ -0,0 +1,84 @@
+package internal
+
+// Symbols of a layer of a multidimensional array or slice
+type ArrayDimension struct {
+	// Symbol at the end of the layer
+	End string
+	// Flag indicating whether lines inside the layer
+	// are indented by one more ArrayIndent or PrettyIndent
+	Indent bool
+	// Symbol between two elements of the layer
+	Sep string
+	// Symbol at the start of the layer
+	Start string
+}
+
+// Constructs symbols of each layer of an array or slice with dims
+// dimensions, the innermost layer first. 1D arrays are enclosed
+// in ArrayStart and ArrayEnd. Layers of multidimensional arrays
+// are separated by ArraySep, ArraySep2D and ArraySep3D, layers above
+// the third dimension by ArraySep2D. 3D and higher layers except
+// the outermost one are written as indented blocks.
+func NewArrayDimensions(o *Options, dims int) []ArrayDimension {
+	res := make([]ArrayDimension, dims)
+
+	for i := range res {
+		res[i] = o.defaultDimension(uint32(dims), uint32(i+1))
+	}
+
+	return res
+}
+
+// Returns symbols of dimension d of an array or slice with dims
+// dimensions. Dimensions without an entry in ArrayDimensions use
+// the last one, nil ArrayDimensions uses NewArrayDimensions.
+func (o *Options) arrayDimension(dims, d uint32) ArrayDimension {
+	if n := uint32(len(o.ArrayDimensions)); n > 0 {
+		return o.ArrayDimensions[min(d, n)-1]
+	}
+
+	return o.defaultDimension(dims, d)
+}
+
+// Returns indentation level of lines of dimension d, the number
+// of indented layers that enclose it
+func (o *Options) arrayLevel(dims, d uint32) int {
+	res := 0
+
+	for outer := d + 1; outer <= dims; outer++ {
+		if o.arrayDimension(dims, outer).Indent {
+			res++
+		}
+	}
+
+	return res
+}
+
+// Returns symbols of dimension d of the preset built by NewArrayDimensions
+func (o *Options) defaultDimension(dims, d uint32) ArrayDimension {
+	if dims == 1 {
+		return ArrayDimension{
+			End:    o.ArrayEnd,
+			Indent: false,
+			Sep:    o.ArraySep,
+			Start:  o.ArrayStart,
+		}
+	}
+
+	res := ArrayDimension{End: "", Indent: false, Sep: o.ArraySep2D, Start: ""}
+
+	switch d {
+	case 1:
+		res.Sep = o.ArraySep
+	case 3:
+		res.Sep = o.ArraySep3D
+	}
+
+	if d >= 3 && d < dims {
+		res.End = o.ArraySep2D + o.ArrayEnd
+		res.Indent = true
+		res.Start = o.ArrayStart + o.ArraySep2D
+	}
+
+	return res
+}
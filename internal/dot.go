@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"fmt"
+	r "reflect"
+	"strconv"
+	"strings"
+)
+
+// Graph of nodes and edges written in DOT
+type dotGraph struct {
+	// Number of nodes, used to name new nodes
+	count int
+	// Edges from ports of fields to nodes, written after all nodes
+	edges []string
+	// IDs of nodes of pointers, maps and slices by their references
+	ids map[reference]string
+	// Nodes that haven't been written yet
+	queue []dotNode
+}
+
+// Node of a graph, a composite value written as a record
+type dotNode struct {
+	id  string
+	val r.Value
+}
+
+// Converts a value to a Graphviz DOT digraph. Structs, maps, slices
+// and arrays are nodes written as records, pointers and nested values
+// are edges. Values shared by pointers are written as a single node.
+func ConvertToDOT(o *Options, val *r.Value) string {
+	c := NewCompositeConverter(withFormat(o, formatDOT), val)
+	return c.ConvertStackToString()
+}
+
+// Constructs an empty graph
+func newDOTGraph() *dotGraph {
+	return &dotGraph{count: 0, edges: nil, ids: map[reference]string{}, queue: nil}
+}
+
+// Converts an array or slice node, each element is a field of the record
+func (c *CompositeConverter) convertDOTArray(it *Item) {
+	if it.ix == 0 && c.isString(it.val) {
+		c.write(dotEscape(bytesToString(it.val)))
+		c.pop()
+		return
+	}
+
+	if it.ix == it.val.Len() {
+		c.pop()
+		return
+	}
+
+	elem := it.val.Index(it.ix)
+	c.convertDOTEntry(it, "["+strconv.Itoa(it.ix)+"]", false, &elem)
+	it.ix++
+}
+
+// Writes a field of a record with a port named by the index of Item it.
+// Composite values are written as nodes connected by an edge,
+// basic values are written after the label.
+func (c *CompositeConverter) convertDOTEntry(
+	it *Item, label string, redact bool, val *r.Value,
+) {
+	port := "f" + strconv.Itoa(it.ix)
+
+	// Fields follow the type of the record
+	c.write("|<" + port + "> " + dotEscape(label))
+
+	if redact {
+		c.write(": " + c.options.RedactedValue)
+		return
+	}
+
+	if id, ok := c.dotNodeOf(*val); ok {
+		c.dot.edges = append(c.dot.edges, it.key+":"+port+" -> "+id)
+		return
+	}
+
+	c.write(": ")
+	c.push(None, 0, val)
+}
+
+// Returns the ID of the node of a composite value. Pointers, maps
+// and slices that already have a node share it, otherwise a new node
+// is queued. Returns false if the value is written as text.
+func (c *CompositeConverter) dotNodeOf(val r.Value) (string, bool) {
+	if val.Kind() == r.Interface && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	if IsNil(&val) || c.hasCustomString(&val) {
+		return "", false
+	}
+
+	switch val.Kind() {
+	case r.Array, r.Slice:
+		if c.isString(&val) {
+			return "", false
+		}
+	case r.Map, r.Pointer, r.Struct:
+	default:
+		return "", false
+	}
+
+	ref, hasRef := newReference(&val)
+
+	if id, ok := c.dot.ids[ref]; hasRef && ok {
+		return id, true
+	}
+
+	id := c.queueDOTNode(unwrapValue(val))
+
+	if hasRef {
+		c.dot.ids[ref] = id
+	}
+
+	return id, true
+}
+
+// Returns a key of a map as a label of a field.
+// Composite keys are converted to JSON.
+func (c *CompositeConverter) formatDOTKey(key *r.Value) string {
+	if key.Kind() == r.Interface && !key.IsNil() {
+		elem := key.Elem()
+		key = &elem
+	}
+
+	if !IsCompositeType(key) {
+		return c.ConvertToString(key)
+	}
+
+	o := *c.options
+	o.format = formatJSON
+	sub := NewCompositeConverter(&o, key)
+	return sub.ConvertStackToString()
+}
+
+// Push the next queued node onto the stack and write the start
+// of its record. Nodes of nil values are written whole without
+// being pushed. Returns false if there are no nodes left.
+func (c *CompositeConverter) pushDOTNode() bool {
+	for c.dot != nil && len(c.dot.queue) > 0 {
+		node := c.dot.queue[0]
+		c.dot.queue = c.dot.queue[1:]
+		c.write("    " + node.id + " [label=\"{")
+
+		if !node.val.IsValid() {
+			c.write("nil}\"];\n")
+			continue
+		}
+
+		label := FormatType(&node.val)
+
+		if label == "" {
+			label = formatTypeName(node.val.Type())
+		}
+
+		c.write(dotEscape(label))
+
+		if !IsCompositeType(&node.val) || IsNil(&node.val) {
+			// Basic value follows the type
+			c.write("|")
+		}
+
+		it := c.push(None, 0, &node.val)
+		it.key = node.id
+		it.suffix = "}\"];\n"
+		return true
+	}
+
+	return false
+}
+
+// Queue a new node and return its ID
+func (c *CompositeConverter) queueDOTNode(val r.Value) string {
+	id := "n" + strconv.Itoa(c.dot.count)
+	c.dot.count++
+	c.dot.queue = append(c.dot.queue, dotNode{id: id, val: val})
+	return id
+}
+
+// Start the graph with the root as its first node
+func (c *CompositeConverter) startDOTGraph(root *Item) {
+	c.write("digraph {\n    node [shape=record];\n")
+	c.stack.Pop()
+
+	if _, ok := c.dotNodeOf(*root.val); !ok {
+		// Basic root is a node of its own
+		c.queueDOTNode(unwrapValue(*root.val))
+	}
+}
+
+// Write edges after all nodes and end the graph
+func (c *CompositeConverter) writeDOTEdges() {
+	for _, edge := range c.dot.edges {
+		c.write(fmt.Sprintf("    %s;\n", edge))
+	}
+
+	c.write("}")
+}
+
+// Escapes characters that have a special meaning in a record label,
+// line breaks are written as escape sequences
+func dotEscape(s string) string {
+	var builder strings.Builder
+
+	for _, char := range s {
+		switch char {
+		case '"', '<', '>', '\\', '{', '|', '}':
+			builder.WriteByte('\\')
+			builder.WriteRune(char)
+		case '\n':
+			builder.WriteString("\\n")
+		case '\r':
+			builder.WriteString("\\r")
+		default:
+			builder.WriteRune(char)
+		}
+	}
+
+	return builder.String()
+}
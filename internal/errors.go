@@ -0,0 +1,85 @@
+package internal
+
+import "strings"
+
+// Formats an error followed by the errors it wraps.
+// For example, fmt.Errorf("open failed: %w", err) yields:
+// open failed -> permission denied
+func formatErrorChain(err error, o *Options) string {
+	var builder strings.Builder
+	writeErrorChain(&builder, err, o)
+	return builder.String()
+}
+
+// Returns errors that aren't nil
+func nonNilErrors(errs []error) []error {
+	res := make([]error, 0, len(errs))
+
+	for _, err := range errs {
+		if err != nil {
+			res = append(res, err)
+		}
+	}
+
+	return res
+}
+
+// Returns the part of a message that isn't repeated in the wrapped message
+func trimWrappedMessage(message, wrapped string) string {
+	if !strings.HasSuffix(message, wrapped) {
+		return message
+	}
+
+	own := strings.TrimSuffix(message, wrapped)
+	return strings.TrimRight(own, ": \n")
+}
+
+// Writes an error followed by the errors it wraps to builder.
+// Errors wrapping multiple errors are written as a list.
+func writeErrorChain(builder *strings.Builder, err error, o *Options) {
+	message := err.Error()
+	var wrapped []error
+
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		if inner := e.Unwrap(); inner != nil {
+			wrapped = []error{inner}
+		}
+	case interface{ Unwrap() []error }:
+		wrapped = nonNilErrors(e.Unwrap())
+	}
+
+	if len(wrapped) == 0 {
+		builder.WriteString(message)
+		return
+	}
+
+	messages := make([]string, len(wrapped))
+
+	for i, inner := range wrapped {
+		messages[i] = inner.Error()
+	}
+
+	// errors.Join separates messages by newlines
+	if own := trimWrappedMessage(message, strings.Join(messages, "\n")); own != "" {
+		builder.WriteString(own)
+		builder.WriteString(o.ErrorChainSep)
+	}
+
+	if len(wrapped) == 1 {
+		writeErrorChain(builder, wrapped[0], o)
+		return
+	}
+
+	builder.WriteString(o.ErrorListStart)
+
+	for i, inner := range wrapped {
+		if i > 0 {
+			builder.WriteString(o.ErrorListSep)
+		}
+
+		writeErrorChain(builder, inner, o)
+	}
+
+	builder.WriteString(o.ErrorListEnd)
+}
@@ -0,0 +1,192 @@
+package internal
+
+const (
+	// Symbols are taken from formatting options
+	formatDefault uint = iota
+	// Graphviz DOT digraph of records linked by pointers and nested values
+	formatDOT
+	// Go source code that can be used as a literal
+	formatGoSyntax
+	// JSON values
+	formatJSON
+	// TOML document
+	formatTOML
+	// Tree with each field, key-value pair and element on its own line
+	formatTree
+	// XML elements
+	formatXML
+	// YAML block collections
+	formatYAML
+)
+
+// Output format with its own symbols, that replace formatting options
+type format struct {
+	// Flag indicating whether lines may be broken to fit LineWidth
+	lineWidth bool
+	// Flag indicating whether pretty mode is supported
+	pretty bool
+	// Replaces formatting options of a copy of Options by the symbols
+	symbols func(res *Options)
+}
+
+// Output formats by their constants
+var formats = map[uint]format{
+	formatDOT: {
+		lineWidth: false,
+		pretty:    false,
+		symbols: func(res *Options) {
+			res.MapEnd = ""
+			res.MapStart = ""
+			res.RedactedValue = dotEscape(res.RedactedValue)
+			res.StructEnd = ""
+			res.StructStart = ""
+		},
+	},
+	formatGoSyntax: {
+		lineWidth: true,
+		pretty:    true,
+		symbols: func(res *Options) {
+			res.ArrayEnd = "}"
+			res.ArraySep = ", "
+			res.ArrayStart = "{"
+			res.CycleEnd = " */"
+			res.CycleStart = "nil /* cycle "
+			res.Elision = "/* ... */"
+			res.MapEnd = "}"
+			res.MapSepKey = ": "
+			res.MapSepVal = ", "
+			res.MapStart = "{"
+			res.ShowFieldNames = true
+			res.StructEnd = "}"
+			res.StructSepFieldName = ": "
+			res.StructSepFieldValue = ", "
+			res.StructStart = "{"
+			res.TagName = ""
+		},
+	},
+	formatJSON: {
+		lineWidth: true,
+		pretty:    true,
+		symbols: func(res *Options) {
+			res.ArrayEnd = "]"
+			res.ArraySep = ", "
+			res.ArrayStart = "["
+			res.CycleEnd = ">"
+			res.CycleStart = "<cycle "
+			res.Elision = "\"...\""
+			res.MapEnd = "}"
+			res.MapSepKey = ": "
+			res.MapSepVal = ", "
+			res.MapStart = "{"
+			res.RedactedValue = jsonQuote(res.RedactedValue)
+			res.ShowFieldNames = true
+			res.StructEnd = "}"
+			res.StructSepFieldName = ": "
+			res.StructSepFieldValue = ", "
+			res.StructStart = "{"
+		},
+	},
+	formatTOML: {
+		lineWidth: false,
+		pretty:    false,
+		symbols: func(res *Options) {
+			res.ArrayEnd = "]"
+			res.ArraySep = ", "
+			res.ArrayStart = "["
+			res.CycleEnd = ">"
+			res.CycleStart = "<cycle "
+			res.Elision = "\"...\""
+			res.MapEnd = "}"
+			res.MapSepKey = " = "
+			res.MapSepVal = ", "
+			res.MapStart = "{"
+			res.RedactedValue = jsonQuote(res.RedactedValue)
+			res.ShowFieldNames = true
+			res.StructEnd = "}"
+			res.StructSepFieldName = " = "
+			res.StructSepFieldValue = ", "
+			res.StructStart = "{"
+		},
+	},
+	formatTree: {
+		lineWidth: false,
+		pretty:    false,
+		symbols: func(res *Options) {
+			res.MapEnd = ""
+			res.MapStart = ""
+			res.StructEnd = ""
+			res.StructStart = ""
+		},
+	},
+	formatXML: {
+		lineWidth: false,
+		pretty:    true,
+		symbols: func(res *Options) {
+			res.CycleEnd = " -->"
+			res.CycleStart = "<!-- cycle "
+			res.Elision = "<!-- ... -->"
+			res.MapEnd = ""
+			res.MapStart = ""
+			res.RedactedValue = xmlEscape(res.RedactedValue)
+			res.StructEnd = ""
+			res.StructStart = ""
+		},
+	},
+	formatYAML: {
+		lineWidth: false,
+		pretty:    false,
+		symbols: func(res *Options) {
+			res.CycleEnd = ">\""
+			res.CycleStart = "\"<cycle "
+			res.Elision = "\"...\""
+			res.MapEnd = ""
+			res.MapSepKey = ":"
+			res.MapStart = ""
+			res.PrettyIndent = "  "
+			res.RedactedValue = yamlQuote(res.RedactedValue)
+			res.ShowFieldNames = true
+			res.StructEnd = ""
+			res.StructSepFieldName = ":"
+			res.StructStart = ""
+		},
+	},
+}
+
+// Returns a copy of Options with the symbols of their output format.
+// Options that annotate, summarize or label values aren't supported
+// by any format and are turned off. Returns o for the default format.
+func newFormatOptions(o *Options) *Options {
+	f, ok := formats[o.format]
+
+	if !ok {
+		return o
+	}
+
+	res := *o
+	res.ArrayDimensions = nil
+	res.MaxElements = 0
+	res.MaxEntries = 0
+	res.PointerAddresses = false
+	res.PointerLabels = false
+	res.ShowDynamicType = false
+	res.ShowLen = false
+	res.ShowType = false
+
+	if !f.lineWidth {
+		res.LineWidth = 0
+	}
+
+	if !f.pretty {
+		res.Pretty = false
+	}
+
+	f.symbols(&res)
+	return &res
+}
+
+// Returns a copy of Options with the output format f
+func withFormat(o *Options, f uint) *Options {
+	res := *o
+	res.format = f
+	return &res
+}
@@ -0,0 +1,395 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	r "reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	gs "github.com/Matej-Chmel/go-generic-stack"
+)
+
+const (
+	// Type of a composite literal is written, basic values are untyped
+	goTyped uint = iota
+	// Type of a composite literal is elided, the value is an element
+	// or a key of an enclosing composite literal
+	goElided
+	// Types are written explicitly including conversions of basic values,
+	// the value is stored in an interface
+	goInterface
+)
+
+// Converts a value to Go source code that can be used as a literal.
+// Returns the code and sorted paths of packages it references.
+func ConvertToGoSyntax(o *Options, val *r.Value) (string, []string) {
+	c := NewCompositeConverter(withFormat(o, formatGoSyntax), val)
+	return c.ConvertStackToString(), c.Imports()
+}
+
+// Writes an array or slice of bytes or runes as a conversion of a string,
+// for example []byte("hello"). Returns false for arrays.
+func (c *CompositeConverter) convertGoBytes(it *Item) bool {
+	if it.val.Kind() != r.Slice || !c.isString(it.val) {
+		return false
+	}
+
+	s := strconv.Quote(bytesToString(it.val))
+	c.write(goConversion(c.formatGoType(it.val.Type()), s))
+	c.pop()
+	return true
+}
+
+// Writes a zero value instead of a composite that is too deep
+// followed by the elision in a comment
+func (c *CompositeConverter) convertGoElided(it *Item) {
+	switch it.val.Kind() {
+	case r.Array, r.Struct:
+		if it.goContext != goElided {
+			c.write(c.formatGoType(it.val.Type()))
+		}
+
+		c.write("{}")
+	default:
+		c.write(c.formatGoNil(it))
+	}
+
+	c.write(" ")
+	c.write(c.options.Elision)
+	c.pop()
+}
+
+// Writes a value of a basic type as a Go literal
+func (c *CompositeConverter) convertGoLeaf(it *Item) {
+	val := it.val
+	var res string
+
+	switch val.Kind() {
+	case r.Bool:
+		res = strconv.FormatBool(val.Bool())
+	case r.Chan:
+		res = c.formatGoChannel(val)
+	case r.Complex64:
+		res = c.formatGoComplex(32, val)
+	case r.Complex128:
+		res = c.formatGoComplex(64, val)
+	case r.Float32:
+		res = c.formatGoFloat(32, val.Float())
+	case r.Float64:
+		res = c.formatGoFloat(64, val.Float())
+	case r.Func:
+		// Functions can't be written as literals
+		res = c.formatGoNil(it) + " /* " + c.formatFunc(val) + " */"
+	case r.Int, r.Int8, r.Int16, r.Int32, r.Int64:
+		res = strconv.FormatInt(val.Int(), 10)
+	case r.Interface, r.Invalid:
+		res = "nil"
+	case r.String:
+		res = strconv.Quote(val.String())
+	case r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64:
+		res = strconv.FormatUint(val.Uint(), 10)
+	case r.Uintptr:
+		res = c.formatUintptr(val)
+	case r.UnsafePointer:
+		c.imports["unsafe"] = true
+		res = fmt.Sprintf("unsafe.Pointer(uintptr(0x%X))", val.Pointer())
+	}
+
+	switch val.Kind() {
+	case r.Chan, r.Func, r.Interface, r.Invalid, r.UnsafePointer:
+		// Already typed
+	default:
+		if it.goContext == goInterface && !isDefaultGoType(val.Type()) {
+			res = goConversion(c.formatGoType(val.Type()), res)
+		}
+	}
+
+	c.write(res)
+}
+
+// Writes a pointer as the address of a composite literal, for example
+// &T{...}. Pointers to other types are written as a function literal
+// that returns the address of a variable.
+func (c *CompositeConverter) convertGoPointer(it *Item, elem *r.Value) {
+	switch elem.Kind() {
+	case r.Array, r.Map, r.Slice, r.Struct:
+		if it.goContext != goElided {
+			// & is elided together with the type
+			c.write("&")
+			it.goContext = goTyped
+		}
+	default:
+		c.write("func() ")
+		c.write(c.formatGoType(it.val.Type()))
+		c.write(" { v := ")
+
+		// Suffixes of outer pointers are written last
+		it.suffix = "; return &v }()" + it.suffix
+		it.goContext = goInterface
+	}
+
+	it.val = elem
+}
+
+// Writes an unexported field in a comment
+func (c *CompositeConverter) convertGoUnexported(it *Item, name string, field *r.Value) {
+	sub := NewCompositeConverter(c.options, field)
+	sub.stack.Top().goContext = goTyped
+	value := strings.ReplaceAll(sub.ConvertStackToString(), "*/", "* /")
+
+	for path := range sub.imports {
+		c.imports[path] = true
+	}
+
+	if c.options.Pretty {
+		// The comment is written on its own line
+		c.writeLine(it.indent + 1)
+	} else if it.ix > 0 {
+		c.write(" ")
+	}
+
+	c.write("/* ")
+	c.write(name)
+	c.write(c.options.StructSepFieldName)
+	c.write(value)
+	c.write(" */")
+}
+
+// Formats a channel as an expression that makes a new channel
+func (c *CompositeConverter) formatGoChannel(val *r.Value) string {
+	typeName := c.formatGoType(val.Type())
+
+	if capacity := val.Cap(); capacity > 0 {
+		return fmt.Sprintf("make(%s, %d)", typeName, capacity)
+	}
+
+	return "make(" + typeName + ")"
+}
+
+// Formats a complex number as a sum of its real and imaginary parts.
+// Infinities and NaN are written as arguments of complex.
+func (c *CompositeConverter) formatGoComplex(bitSize int, val *r.Value) string {
+	complex := val.Complex()
+	realPart := c.formatGoFloat(bitSize, real(complex))
+	imagPart := c.formatGoFloat(bitSize, imag(complex))
+
+	if strings.HasPrefix(realPart, "math.") || strings.HasPrefix(imagPart, "math.") {
+		// Function calls can't have the imaginary suffix and
+		// an infinite real part would overflow the constant
+		return "complex(" + realPart + ", " + imagPart + ")"
+	}
+
+	return "(" + realPart + " + " + imagPart + "i)"
+}
+
+// Formats a floating-point number with all of its digits, infinities and NaN
+// are written as calls of functions from package math
+func (c *CompositeConverter) formatGoFloat(bitSize int, f float64) string {
+	switch {
+	case math.IsNaN(f):
+		c.imports["math"] = true
+		return "math.NaN()"
+	case math.IsInf(f, 1):
+		c.imports["math"] = true
+		return "math.Inf(1)"
+	case math.IsInf(f, -1):
+		c.imports["math"] = true
+		return "math.Inf(-1)"
+	}
+
+	s := strconv.FormatFloat(f, 'g', -1, bitSize)
+
+	if !strings.ContainsAny(s, ".e") {
+		// Keep the constant untyped floating-point
+		s += ".0"
+	}
+
+	return s
+}
+
+// Formats a nil value, nil stored in an interface is converted to its type
+func (c *CompositeConverter) formatGoNil(it *Item) string {
+	if it.goContext != goInterface || !it.val.IsValid() ||
+		it.val.Kind() == r.Interface {
+		return "nil"
+	}
+
+	return goConversion(c.formatGoType(it.val.Type()), "nil")
+}
+
+// Returns the name of a type in Go syntax. Named types are qualified
+// by their package unless it's GoSyntaxPackage or main, the package
+// is then added to imports.
+func (c *CompositeConverter) formatGoType(aType r.Type) string {
+	var builder strings.Builder
+	stack := gs.Stack[goTypeInfo]{}
+	stack.Push(goTypeInfo{aType: aType, text: ""})
+
+	for stack.HasItems() {
+		top := stack.Top()
+		stack.Pop()
+
+		if top.aType == nil {
+			builder.WriteString(top.text)
+			continue
+		}
+
+		aType := top.aType
+
+		if name := aType.Name(); name != "" {
+			qualifier, _, _ := strings.Cut(aType.String(), ".")
+
+			// Package main can't be imported, its types are written unqualified
+			if path := aType.PkgPath(); path != "" && qualifier != "main" &&
+				path != c.options.GoSyntaxPackage {
+				c.imports[path] = true
+				builder.WriteString(qualifier)
+				builder.WriteRune('.')
+			}
+
+			builder.WriteString(name)
+			continue
+		}
+
+		// Parts of the type are pushed in reverse order
+		switch aType.Kind() {
+		case r.Array:
+			builder.WriteString(fmt.Sprintf("[%d]", aType.Len()))
+			stack.Push(goTypeInfo{aType: aType.Elem(), text: ""})
+		case r.Chan:
+			switch aType.ChanDir() {
+			case r.RecvDir:
+				builder.WriteString("<-chan ")
+			case r.SendDir:
+				builder.WriteString("chan<- ")
+			default:
+				builder.WriteString("chan ")
+			}
+
+			stack.Push(goTypeInfo{aType: aType.Elem(), text: ""})
+		case r.Func:
+			builder.WriteString("func(")
+			out := aType.NumOut()
+
+			if out > 1 {
+				// Multiple output parameters are enclosed in brackets
+				stack.Push(goTypeInfo{aType: nil, text: ")"})
+			}
+
+			pushGoTypes(&stack, out, aType.Out)
+
+			if out > 1 {
+				stack.Push(goTypeInfo{aType: nil, text: " ("})
+			} else if out == 1 {
+				stack.Push(goTypeInfo{aType: nil, text: " "})
+			}
+
+			stack.Push(goTypeInfo{aType: nil, text: ")"})
+			pushGoTypes(&stack, aType.NumIn(), aType.In)
+		case r.Interface:
+			if aType.NumMethod() == 0 {
+				builder.WriteString("any")
+			} else {
+				builder.WriteString(aType.String())
+			}
+		case r.Map:
+			builder.WriteString("map[")
+			stack.Push(goTypeInfo{aType: aType.Elem(), text: ""})
+			stack.Push(goTypeInfo{aType: nil, text: "]"})
+			stack.Push(goTypeInfo{aType: aType.Key(), text: ""})
+		case r.Pointer:
+			builder.WriteRune('*')
+			stack.Push(goTypeInfo{aType: aType.Elem(), text: ""})
+		case r.Slice:
+			builder.WriteString("[]")
+			stack.Push(goTypeInfo{aType: aType.Elem(), text: ""})
+		case r.Struct:
+			builder.WriteString("struct{")
+			stack.Push(goTypeInfo{aType: nil, text: "}"})
+
+			for i := aType.NumField() - 1; i >= 0; i-- {
+				field := aType.Field(i)
+				stack.Push(goTypeInfo{aType: field.Type, text: ""})
+
+				if !field.Anonymous {
+					stack.Push(goTypeInfo{aType: nil, text: field.Name + " "})
+				}
+
+				if i > 0 {
+					stack.Push(goTypeInfo{aType: nil, text: "; "})
+				}
+			}
+		default:
+			builder.WriteString(aType.String())
+		}
+	}
+
+	return builder.String()
+}
+
+// Returns sorted paths of packages referenced by the written Go syntax
+func (c *CompositeConverter) Imports() []string {
+	res := make([]string, 0, len(c.imports))
+
+	for path := range c.imports {
+		res = append(res, path)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// Write the type of a composite literal unless it's elided
+func (c *CompositeConverter) writeGoType(it *Item) {
+	if c.options.format == formatGoSyntax && it.goContext != goElided {
+		c.write(c.formatGoType(it.val.Type()))
+	}
+}
+
+// Formats a conversion of a value to a type
+func goConversion(typeName, value string) string {
+	switch {
+	case strings.HasPrefix(typeName, "*"),
+		strings.HasPrefix(typeName, "<-"),
+		strings.HasPrefix(typeName, "chan"),
+		strings.HasPrefix(typeName, "func"):
+		return "(" + typeName + ")(" + value + ")"
+	}
+
+	return typeName + "(" + value + ")"
+}
+
+// Internal struct for a Type or a piece of text in a stack
+type goTypeInfo struct {
+	// Type to format, nil if text should be written instead
+	aType r.Type
+	text  string
+}
+
+// Returns true if an untyped constant of the type defaults to the type
+func isDefaultGoType(aType r.Type) bool {
+	if aType.PkgPath() != "" {
+		return false
+	}
+
+	switch aType.Name() {
+	case "bool", "complex128", "float64", "int", "string":
+		return true
+	}
+
+	return false
+}
+
+// Push n types returned by getType in reverse order
+// so that they are written separated by commas
+func pushGoTypes(stack *gs.Stack[goTypeInfo], n int, getType func(int) r.Type) {
+	for i := n - 1; i >= 0; i-- {
+		stack.Push(goTypeInfo{aType: getType(i), text: ""})
+
+		if i > 0 {
+			stack.Push(goTypeInfo{aType: nil, text: ", "})
+		}
+	}
+}
@@ -4,16 +4,39 @@ import "reflect"
 
 // Item in a stack
 type Item struct {
+	// Number of elements or fields already written
+	count int
+	// Number of composite layers above this Item, 0 for the first Item
+	depth int
 	// Two-part bit array, higher 16 bits represent number of dimensions
 	// of the original array or slice, lower 16 bits number of dimensions
 	// of the current layer. It's 0, if this Item isn't an array or slice.
 	dim uint32
 	// Flag indicating the current stage of processing
 	flag uint
+	// Context of a value written as Go syntax,
+	// determines whether its type is written
+	goContext uint
+	// Indentation level of this Item in pretty mode
+	indent int
 	// If Item is an array or slice, ix is an index into that data
 	ix int
+	// Dotted key of a TOML table or value, the start tag
+	// of repeated XML elements or the ID of a DOT node
+	key string
 	// If Item is a map, the order of keys is saved here
 	keys []reflect.Value
+	// Flag indicating whether a document nest was opened for this Item
+	nested bool
+	// Path of a TOML value in error messages, the dotted key
+	// with indices of elements of arrays
+	path string
+	// Connectors of a tree written before entries of this Item
+	prefix string
+	// References registered by this Item, released when it's popped
+	refs []reference
+	// Text written after the value when the Item is popped
+	suffix string
 	// If Item is a struct and field names should be written,
 	// save the type
 	typ reflect.Type
@@ -26,6 +49,9 @@ const (
 	None uint = iota
 	// Item is a part of a byte array of slice
 	Bytes
+	// Item is an interface whose dynamic type should be written
+	// after its dynamic value
+	DynamicType
 	// Item is a part of multidimensional array of slice
 	InnerDim
 	// Item is a map and a key should be processed in the next stage
@@ -34,6 +60,13 @@ const (
 	Runes
 	// Item is a struct and a pointer to this struct has been created
 	StructData
+	// Item is a TOML table whose key/value pairs have been written
+	// and whose tables should be written next
+	TableSections
+	// Item is a TOML table whose key/value pairs should be written next
+	TableValues
+	// Item is an array of TOML tables
+	Tables
 	// Item is a map and a value should be processed in the next stage
 	ValueNext
 )
@@ -41,12 +74,22 @@ const (
 // Constructs a new Item
 func NewItem(flag uint, index int, val *reflect.Value) *Item {
 	return &Item{
-		dim:  0,
-		flag: flag,
-		ix:   index,
-		keys: nil,
-		typ:  nil,
-		val:  val,
+		count:     0,
+		depth:     0,
+		dim:       0,
+		flag:      flag,
+		goContext: goTyped,
+		indent:    0,
+		ix:        index,
+		key:       "",
+		keys:      nil,
+		nested:    false,
+		path:      "",
+		prefix:    "",
+		refs:      nil,
+		suffix:    "",
+		typ:       nil,
+		val:       val,
 	}
 }
 
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	r "reflect"
+	"strconv"
+	"strings"
+)
+
+// Converts a value to JSON
+func ConvertToJSON(o *Options, val *r.Value) string {
+	c := NewCompositeConverter(withFormat(o, formatJSON), val)
+	return c.ConvertStackToString()
+}
+
+// Writes an array or slice of bytes or runes as a JSON string.
+// Returns false if it shouldn't be written as a string.
+func (c *CompositeConverter) convertJSONBytes(it *Item) bool {
+	if !c.isString(it.val) {
+		return false
+	}
+
+	c.write(jsonQuote(bytesToString(it.val)))
+	c.pop()
+	return true
+}
+
+// Writes a value of a basic type as a JSON value. Values that have
+// no JSON equivalent are written as strings that describe them.
+func (c *CompositeConverter) convertJSONLeaf(it *Item) {
+	val := it.val
+
+	switch val.Kind() {
+	case r.Bool:
+		c.write(c.formatBool(val))
+	case r.Float32, r.Float64:
+		if f := val.Float(); math.IsInf(f, 0) || math.IsNaN(f) {
+			c.write(jsonQuote(strconv.FormatFloat(f, 'g', -1, 64)))
+		} else {
+			c.write(c.ConvertToString(val))
+		}
+	case r.Int, r.Int8, r.Int16, r.Int32, r.Int64:
+		c.write(c.formatInt(val))
+	case r.Interface, r.Invalid:
+		c.write("null")
+	case r.String:
+		c.write(jsonQuote(val.String()))
+	case r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64:
+		c.write(c.formatUint(val))
+	default:
+		c.write(jsonQuote(c.ConvertToString(val)))
+	}
+}
+
+// Returns a key of a map as a JSON string. Other keys are converted
+// to JSON and quoted, unless the conversion already is a JSON string.
+func (c *CompositeConverter) formatJSONKey(key *r.Value) string {
+	if key.Kind() == r.String {
+		return jsonQuote(key.String())
+	}
+
+	o := *c.options
+	o.LineWidth = 0
+	o.Pretty = false
+	sub := NewCompositeConverter(&o, key)
+	res := sub.ConvertStackToString()
+
+	if strings.HasPrefix(res, "\"") {
+		return res
+	}
+
+	return jsonQuote(res)
+}
+
+// Quotes a string and escapes characters that aren't allowed in a JSON
+// string. Invalid UTF-8 is replaced by the replacement character.
+func jsonQuote(s string) string {
+	var builder strings.Builder
+	builder.WriteByte('"')
+
+	for _, char := range s {
+		switch char {
+		case '"', '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(char)
+		case '\b':
+			builder.WriteString("\\b")
+		case '\f':
+			builder.WriteString("\\f")
+		case '\n':
+			builder.WriteString("\\n")
+		case '\r':
+			builder.WriteString("\\r")
+		case '\t':
+			builder.WriteString("\\t")
+		default:
+			if char < 0x20 || char == 0x7F {
+				builder.WriteString(fmt.Sprintf("\\u%04x", char))
+			} else {
+				// Invalid bytes are decoded as utf8.RuneError
+				builder.WriteRune(char)
+			}
+		}
+	}
+
+	builder.WriteByte('"')
+	return builder.String()
+}
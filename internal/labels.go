@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"fmt"
+	r "reflect"
+	"strconv"
+)
+
+// Labels of pointers that are referenced more than once
+type pointerLabels struct {
+	// Flag indicating whether references are being counted
+	// by the first conversion
+	counting bool
+	// Number of written references to each pointer
+	counts map[reference]int
+	// Labels of pointers whose targets have been written
+	labels map[reference]int
+}
+
+// Counts references to pointers written when val is converted.
+// The value is converted once without labels, so that references
+// elided by summaries, MaxDepth or custom methods aren't counted.
+func newPointerLabels(o *Options, val r.Value) *pointerLabels {
+	res := &pointerLabels{
+		counting: true,
+		counts:   map[reference]int{},
+		labels:   map[reference]int{},
+	}
+
+	countOptions := *o
+	countOptions.LineWidth = 0
+	countOptions.PointerLabels = false
+	c := NewCompositeConverter(&countOptions, &val)
+	c.labels = res
+	c.ConvertStackToString()
+	res.counting = false
+	return res
+}
+
+// Writes the label of a pointer referenced more than once before
+// its first occurrence. Later occurrences are replaced by the label,
+// in that case pops the Item and returns true.
+func (c *CompositeConverter) convertLabel(it *Item) bool {
+	ref, ok := newReference(it.val)
+
+	if !ok || c.labels == nil {
+		return false
+	}
+
+	if c.labels.counting {
+		// Later occurrences aren't converted, like in the labeled output
+		if c.labels.counts[ref]++; c.labels.counts[ref] > 1 {
+			c.pop()
+			return true
+		}
+
+		return false
+	}
+
+	if c.labels.counts[ref] < 2 {
+		return false
+	}
+
+	if label, ok := c.labels.labels[ref]; ok {
+		c.write(c.options.LabelStart + strconv.Itoa(label))
+		c.pop()
+		return true
+	}
+
+	label := len(c.labels.labels) + 1
+	c.labels.labels[ref] = label
+	c.write(c.options.LabelStart + strconv.Itoa(label) + c.options.LabelSep)
+	return false
+}
+
+// Returns the address of a pointer in hexadecimal
+func formatAddress(val *r.Value) string {
+	return fmt.Sprintf("%#x", val.Pointer())
+}
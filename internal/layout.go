@@ -0,0 +1,259 @@
+package internal
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	gs "github.com/Matej-Chmel/go-generic-stack"
+)
+
+const (
+	// Node is a piece of text
+	docText uint = iota
+	// Node is a piece of text whose lines that follow
+	// its line breaks aren't indented
+	docRaw
+	// Node is a line break that is written as its flat text
+	// if the enclosing group fits on the line
+	docLine
+	// Node is a group of nodes that is either written on one line
+	// or all of its line breaks are broken
+	docGroup
+	// Node is a sequence of nodes indented one level deeper
+	// when its line breaks are broken
+	docNest
+)
+
+// Node of a document tree
+type docNode struct {
+	// Text written before a broken line break
+	broken string
+	// Child nodes of a group or nest
+	children []*docNode
+	// Kind of the node
+	kind uint
+	// Text of a text node or flat text of a line break
+	text string
+}
+
+// Document tree built from text, line breaks, groups and nests.
+// The tree is rendered in the style of Wadler's and Oppen's pretty printers.
+type document struct {
+	// Groups and nests that haven't been closed yet
+	open gs.Stack[*docNode]
+	// The root of the tree
+	root *docNode
+}
+
+// Constructs an empty document
+func newDocument() *document {
+	d := &document{
+		open: gs.Stack[*docNode]{},
+		root: &docNode{broken: "", children: nil, kind: docGroup, text: ""},
+	}
+	d.open.Push(d.root)
+	return d
+}
+
+// Appends a node to the innermost open group or nest
+func (d *document) add(node *docNode) {
+	top := d.open.Top()
+	top.children = append(top.children, node)
+}
+
+// Closes the innermost open group or nest
+func (d *document) close() {
+	d.open.Pop()
+}
+
+// Appends a line break with flat text and text written before the break
+// if the line break is broken
+func (d *document) line(flat, broken string) {
+	d.add(&docNode{broken: broken, children: nil, kind: docLine, text: flat})
+}
+
+// Opens a new group or nest
+func (d *document) openNode(kind uint) {
+	node := &docNode{broken: "", children: nil, kind: kind, text: ""}
+	d.add(node)
+	d.open.Push(node)
+}
+
+// Renders the document so that groups fit within width if possible.
+// Broken line breaks are indented by indent for each level of nesting.
+func (d *document) render(builder *strings.Builder, width int, indent string) {
+	rd := renderer{builder: builder, column: 0, indent: indent, lineStart: false}
+	stack := gs.Stack[renderItem]{}
+	stack.Push(renderItem{flat: false, level: 0, node: d.root, rest: 0})
+
+	for stack.HasItems() {
+		top := stack.Top()
+		stack.Pop()
+
+		switch top.node.kind {
+		case docText:
+			rd.writeText(top.level, top.node.text)
+		case docRaw:
+			first, next, found := strings.Cut(top.node.text, "\n")
+			rd.writeText(top.level, first)
+
+			if found {
+				rd.writeText(0, "\n"+next)
+			}
+		case docLine:
+			if top.flat {
+				rd.writeText(top.level, top.node.text)
+			} else if top.node.broken == "" && rd.lineStart {
+				// Line has just been broken, no empty line is written
+				continue
+			} else {
+				rd.writeText(top.level, top.node.broken+"\n")
+			}
+		case docGroup, docNest:
+			flat := top.flat
+
+			if top.node.kind == docGroup && !flat {
+				flat = fits(top.node, width-rd.column-top.rest)
+			}
+
+			level := top.level
+
+			if top.node.kind == docNest {
+				level++
+			}
+
+			// Push children in reverse order so that the first one is on top
+			rest := top.rest
+
+			for i := len(top.node.children) - 1; i >= 0; i-- {
+				child := top.node.children[i]
+				stack.Push(renderItem{flat: flat, level: level, node: child, rest: rest})
+				rest = leadingWidth(child, rest)
+			}
+		}
+	}
+}
+
+// Appends a text node whose lines aren't indented
+func (d *document) raw(s string) {
+	if s != "" {
+		d.add(&docNode{broken: "", children: nil, kind: docRaw, text: s})
+	}
+}
+
+// Appends a text node
+func (d *document) text(s string) {
+	if s != "" {
+		d.add(&docNode{broken: "", children: nil, kind: docText, text: s})
+	}
+}
+
+// Returns true if the node written on one line is at most width long.
+// Text with a line break never fits. The text that follows the node
+// up to the next line break is subtracted from width by the caller.
+func fits(node *docNode, width int) bool {
+	stack := gs.Stack[*docNode]{}
+	stack.Push(node)
+
+	for stack.HasItems() && width >= 0 {
+		top := stack.Top()
+		stack.Pop()
+
+		switch top.kind {
+		case docText, docRaw, docLine:
+			if strings.IndexByte(top.text, '\n') >= 0 {
+				return false
+			}
+
+			width -= utf8.RuneCountInString(top.text)
+		default:
+			for i := len(top.children) - 1; i >= 0; i-- {
+				stack.Push(top.children[i])
+			}
+		}
+	}
+
+	return width >= 0
+}
+
+// Returns the width of a node up to its first line break including
+// the text written before the break. If the node has no line break,
+// the width of the text that follows it, rest, is added.
+func leadingWidth(node *docNode, rest int) int {
+	width := 0
+	stack := gs.Stack[*docNode]{}
+	stack.Push(node)
+
+	for stack.HasItems() {
+		top := stack.Top()
+		stack.Pop()
+
+		switch top.kind {
+		case docText, docRaw:
+			if i := strings.IndexByte(top.text, '\n'); i >= 0 {
+				return width + utf8.RuneCountInString(top.text[:i])
+			}
+
+			width += utf8.RuneCountInString(top.text)
+		case docLine:
+			return width + utf8.RuneCountInString(top.broken)
+		default:
+			for i := len(top.children) - 1; i >= 0; i-- {
+				stack.Push(top.children[i])
+			}
+		}
+	}
+
+	return width + rest
+}
+
+// Node of a document waiting to be rendered
+type renderItem struct {
+	// The node is written on one line
+	flat bool
+	// Indentation level of broken line breaks
+	level int
+	// The node to render
+	node *docNode
+	// Width of the text that follows the node up to the next
+	// line break that may be broken
+	rest int
+}
+
+// Writes rendered text and tracks the current column
+type renderer struct {
+	builder *strings.Builder
+	// Width of the current line
+	column int
+	// Indentation symbol for one level
+	indent string
+	// Flag indicating whether a line break has just been written
+	lineStart bool
+}
+
+// Writes text, lines that follow a line break are indented by level
+func (rd *renderer) writeText(level int, text string) {
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			rd.builder.WriteByte('\n')
+			rd.column = 0
+			rd.lineStart = true
+		}
+
+		if line == "" {
+			continue
+		}
+
+		if rd.lineStart {
+			for j := 0; j < level; j++ {
+				rd.builder.WriteString(rd.indent)
+			}
+
+			rd.column = level * utf8.RuneCountInString(rd.indent)
+			rd.lineStart = false
+		}
+
+		rd.builder.WriteString(line)
+		rd.column += utf8.RuneCountInString(line)
+	}
+}
@@ -18,6 +18,12 @@ func NewLeafConverter(o *Options) LeafConverter {
 
 // Converts basic built-in types to string
 func (c *LeafConverter) ConvertToString(val *r.Value) string {
+	if val.IsValid() {
+		if f, ok := c.options.formatter(val.Type()); ok {
+			return f(*val)
+		}
+	}
+
 	switch val.Kind() {
 	case r.Bool:
 		return c.formatBool(val)
@@ -82,7 +88,7 @@ func (c *LeafConverter) formatByte(val *r.Value) string {
 
 // Formats a channel
 func (c *LeafConverter) formatChannel(val *r.Value) string {
-	return "chan " + val.Type().Elem().String()
+	return "chan " + val.Type().Elem().String() + c.formatLength(val)
 }
 
 // Formats a complex number
@@ -158,13 +164,34 @@ func (c *LeafConverter) formatInt(val *r.Value) string {
 	return strconv.FormatInt(val.Int(), 10)
 }
 
-// Formats an interface
+// Formats an interface by its dynamic value
 func (c *LeafConverter) formatInterface(val *r.Value) string {
 	if !val.IsValid() || val.IsZero() {
 		return "nil"
 	}
 
-	return "interface{}"
+	if elem := val.Elem(); !IsCompositeType(&elem) {
+		return c.ConvertToString(&elem)
+	}
+
+	// Composite values are converted by the CompositeConverter
+	return FormatType(val)
+}
+
+// Formats an annotation of the length of a value, capacity of slices
+// and channels follows it. Returns empty string unless ShowLen is set.
+func (c *LeafConverter) formatLength(val *r.Value) string {
+	if !c.options.ShowLen {
+		return ""
+	}
+
+	res := c.options.LenStart + strconv.Itoa(val.Len())
+
+	if kind := val.Kind(); kind == r.Chan || kind == r.Slice {
+		res += c.options.CapSep + strconv.Itoa(val.Cap())
+	}
+
+	return res + c.options.LenEnd
 }
 
 // Formats a rune as a character
@@ -174,7 +201,7 @@ func (c *LeafConverter) formatRune(val *r.Value) string {
 
 // Formats a string
 func (c *LeafConverter) formatString(val *r.Value) string {
-	return val.String()
+	return val.String() + c.formatLength(val)
 }
 
 // Formats an unsinged integer
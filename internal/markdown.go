@@ -0,0 +1,72 @@
+package internal
+
+import (
+	r "reflect"
+	"strings"
+)
+
+// Converts a 2D array or slice or an array or slice of structs
+// to a GitHub-flavored markdown table, columns of numbers are aligned
+// to the right. Empty slices of structs are written as a header only,
+// other arrays and slices without cells as [] in a fenced code block.
+// Returns false if the value isn't tabular.
+func ConvertToMarkdown(o *Options, val *r.Value) (string, bool) {
+	if unwrapValue(*val).Kind() == r.Map {
+		return "", false
+	}
+
+	t, ok := newTable(newCellOptions(o), val, false)
+
+	if !ok {
+		if hasNoCells(val) {
+			// Table needs a header, 2D arrays without cells have no text of their own
+			return FencedCodeBlock(o.ArrayStart + o.ArrayEnd), true
+		}
+
+		return "", false
+	}
+
+	var builder strings.Builder
+	writeMarkdownRow(&builder, t.header)
+	delimiters := make([]string, len(t.header))
+
+	for i := range delimiters {
+		if delimiters[i] = "---"; t.isNumeric(i) {
+			delimiters[i] = "---:"
+		}
+	}
+
+	writeMarkdownRow(&builder, delimiters)
+
+	for _, row := range t.rows {
+		writeMarkdownRow(&builder, t.rowTexts(row))
+	}
+
+	return strings.TrimSuffix(builder.String(), "\n"), true
+}
+
+// Encloses text in a fenced code block. The fence is longer
+// than any run of backticks in the text.
+func FencedCodeBlock(s string) string {
+	fence := "```"
+
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+
+	return fence + "\n" + s + "\n" + fence
+}
+
+// Write a row of a markdown table. Pipes are escaped
+// and line breaks are replaced by HTML breaks.
+func writeMarkdownRow(builder *strings.Builder, cells []string) {
+	builder.WriteByte('|')
+
+	for _, cell := range cells {
+		cell = strings.ReplaceAll(cell, "|", "\\|")
+		cell = strings.ReplaceAll(cell, "\n", "<br>")
+		builder.WriteString(" " + cell + " |")
+	}
+
+	builder.WriteByte('\n')
+}
@@ -0,0 +1,267 @@
+package internal
+
+import (
+	"math"
+	"math/cmplx"
+	r "reflect"
+	"strings"
+)
+
+// Symbols of numbers in the notation of a matrix
+type matrixNotation struct {
+	// Imaginary unit of complex numbers
+	imaginary string
+	// Positive infinity, negative infinity is prefixed by a minus sign
+	inf string
+	// Not a number
+	nan string
+}
+
+var (
+	// Symbols of LaTeX math mode
+	latexNotation = matrixNotation{imaginary: "i", inf: "\\infty", nan: "\\mathrm{NaN}"}
+	// Symbols of NumPy
+	numpyNotation = matrixNotation{imaginary: "j", inf: "np.inf", nan: "np.nan"}
+)
+
+// Converts a 2D or 3D numeric array or slice to LaTeX matrices with
+// columns aligned to the right. Each 2D slice of a 3D array is a matrix
+// of its own. Returns false if the value isn't a numeric matrix.
+func ConvertToLaTeX(o *Options, val *r.Value) (string, bool) {
+	slabs, widths, ok := matrixSlabs(o, val, &latexNotation)
+
+	if !ok {
+		return "", false
+	}
+
+	matrices := make([]string, len(slabs))
+
+	for i, slab := range slabs {
+		var builder strings.Builder
+		builder.WriteString("\\begin{" + o.LaTeXMatrix + "}\n")
+
+		for j, row := range slab {
+			builder.WriteString(strings.Join(alignMatrixRow(row, widths), " & "))
+
+			if j < len(slab)-1 {
+				builder.WriteString(" \\\\")
+			}
+
+			builder.WriteByte('\n')
+		}
+
+		builder.WriteString("\\end{" + o.LaTeXMatrix + "}")
+		matrices[i] = builder.String()
+	}
+
+	return strings.Join(matrices, ",\n"), true
+}
+
+// Converts a 2D or 3D numeric array or slice to a NumPy array literal
+// with columns aligned to the right. Ragged arrays are written
+// with dtype=object, NumPy can't make a matrix of them. Returns false
+// if the value isn't a numeric matrix.
+func ConvertToNumPy(o *Options, val *r.Value) (string, bool) {
+	slabs, widths, ok := matrixSlabs(o, val, &numpyNotation)
+
+	if !ok {
+		return "", false
+	}
+
+	elem := unwrapValue(*val)
+	threeD := countDimensions(&elem) == 3
+	indent := "       "
+	var builder strings.Builder
+	builder.WriteString("array([")
+
+	if threeD {
+		indent += " "
+		builder.WriteByte('[')
+	}
+
+	for i, slab := range slabs {
+		if i > 0 {
+			// Slices of a 3D array are separated by an empty line
+			builder.WriteString("],\n\n" + indent[1:] + "[")
+		}
+
+		for j, row := range slab {
+			if j > 0 {
+				builder.WriteString(",\n" + indent)
+			}
+
+			builder.WriteString("[" + strings.Join(alignMatrixRow(row, widths), ", ") + "]")
+		}
+	}
+
+	if threeD {
+		builder.WriteByte(']')
+	}
+
+	builder.WriteString("]")
+
+	if isRagged(slabs) {
+		builder.WriteString(", dtype=object")
+	}
+
+	builder.WriteString(")")
+	return builder.String(), true
+}
+
+// Returns cells of a row padded to the widths of their columns
+func alignMatrixRow(row []string, widths []int) []string {
+	res := make([]string, len(row))
+
+	for i, cell := range row {
+		res[i] = strings.Repeat(" ", widths[i]-textWidth(cell)) + cell
+	}
+
+	return res
+}
+
+// Returns a number as a cell of a matrix. Complex numbers are written
+// without parentheses, infinities and NaN are written as symbols
+// of the notation. Returns false for complex numbers with parts
+// that aren't finite, they have no symbol.
+func formatMatrixCell(c *LeafConverter, val r.Value, n *matrixNotation) (string, bool) {
+	if _, ok := c.options.formatter(val.Type()); ok {
+		return c.ConvertToString(&val), true
+	}
+
+	switch val.Kind() {
+	case r.Complex64, r.Complex128:
+		if z := val.Complex(); cmplx.IsInf(z) || cmplx.IsNaN(z) {
+			return "", false
+		}
+
+		bits := 64
+
+		if val.Kind() == r.Complex64 {
+			bits = 32
+		}
+
+		return matrixComplex(c.formatComplex(bits, &val), n.imaginary), true
+	case r.Float32, r.Float64:
+		switch f := val.Float(); {
+		case math.IsNaN(f):
+			return n.nan, true
+		case math.IsInf(f, 1):
+			return n.inf, true
+		case math.IsInf(f, -1):
+			return "-" + n.inf, true
+		}
+	}
+
+	return c.ConvertToString(&val), true
+}
+
+// Returns true if elements of the given type are written as numbers
+func isMatrixElem(o *Options, aType r.Type) bool {
+	switch aType.Kind() {
+	case r.Complex64, r.Complex128, r.Float32, r.Float64, r.Int, r.Int8,
+		r.Int16, r.Int64, r.Uint, r.Uint16, r.Uint32, r.Uint64, r.Uintptr:
+		return true
+	case r.Int32:
+		// Runes may be written as characters
+		return !o.RuneAsString
+	case r.Uint8:
+		return !o.ByteAsString
+	}
+
+	return false
+}
+
+// Returns true if rows or 2D slices of a matrix differ in length
+func isRagged(slabs [][][]string) bool {
+	for _, slab := range slabs {
+		if len(slab) != len(slabs[0]) {
+			return true
+		}
+
+		for _, row := range slab {
+			if len(row) != len(slabs[0][0]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Returns a complex number written by formatComplex
+// in the notation of a matrix
+func matrixComplex(s string, imaginary string) string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "("), "i)")
+	s = strings.Replace(s, "+-", "-", 1)
+	return s + imaginary
+}
+
+// Returns cells of a 2D or 3D numeric array or slice as 2D slices
+// of rows and the width of each column across all of them. Returns false
+// if the value isn't a numeric matrix, has no rows or has a cell
+// that can't be written in the notation.
+func matrixSlabs(o *Options, val *r.Value, n *matrixNotation) ([][][]string, []int, bool) {
+	elem := unwrapValue(*val)
+	kind := elem.Kind()
+
+	if kind != r.Array && kind != r.Slice {
+		return nil, nil, false
+	}
+
+	dims := countDimensions(&elem)
+
+	if dims != 2 && dims != 3 {
+		return nil, nil, false
+	}
+
+	elemType := elem.Type()
+
+	for i := uint32(0); i < dims; i++ {
+		elemType = elemType.Elem()
+	}
+
+	if !isMatrixElem(o, elemType) || elem.Len() == 0 {
+		return nil, nil, false
+	}
+
+	var slabValues []r.Value
+
+	if dims == 2 {
+		slabValues = []r.Value{elem}
+	} else {
+		for i := 0; i < elem.Len(); i++ {
+			slabValues = append(slabValues, elem.Index(i))
+		}
+	}
+
+	c := NewLeafConverter(o)
+	slabs := make([][][]string, len(slabValues))
+	widths := []int{}
+
+	for i, slab := range slabValues {
+		slabs[i] = make([][]string, slab.Len())
+
+		for j := range slabs[i] {
+			row := slab.Index(j)
+			slabs[i][j] = make([]string, row.Len())
+
+			for k := range slabs[i][j] {
+				cell, ok := formatMatrixCell(&c, row.Index(k), n)
+
+				if !ok {
+					return nil, nil, false
+				}
+
+				slabs[i][j][k] = cell
+
+				if k == len(widths) {
+					widths = append(widths, 0)
+				}
+
+				widths[k] = max(widths[k], textWidth(cell))
+			}
+		}
+	}
+
+	return slabs, widths, true
+}
@@ -1,10 +1,22 @@
 package internal
 
+import r "reflect"
+
 type Options struct {
+	// Flag indicating whether cells of each 2D layer of an array or slice
+	// should be padded to the widest cell of their column. Numbers are
+	// aligned to the right, other values to the left, default false
+	AlignColumns bool
+	// Symbols of each layer of a multidimensional array or slice,
+	// the innermost layer first. Layers without an entry use the last one.
+	// Nil uses NewArrayDimensions built from ArrayStart, ArraySep,
+	// ArraySep2D, ArraySep3D and ArrayEnd, default nil
+	ArrayDimensions []ArrayDimension
 	// Symbol at the end of an array or slice, default "]"
 	ArrayEnd string
 	// Indentation symbol used to indent layers in multidimensional array
-	// or slice, default 4 spaces
+	// or slice, pretty mode and LineWidth use PrettyIndent instead,
+	// default 4 spaces
 	ArrayIndent string
 	// Separator between two elements of an array or slice, default " "
 	ArraySep string
@@ -17,6 +29,36 @@ type Options struct {
 	// Flag indicating whether a byte array or slice should be written
 	// as a string, default false
 	ByteAsString bool
+	// Symbol between the length and capacity of an annotation
+	// written by ShowLen, default " cap="
+	CapSep string
+	// Symbol at the end of a marker that replaces a pointer, map or slice
+	// that contains itself, default ">"
+	CycleEnd string
+	// Symbol at the start of a marker that replaces a pointer, map or slice
+	// that contains itself, default "<cycle "
+	CycleStart string
+	// Symbol after the dynamic type of an interface, default ")"
+	DynamicTypeEnd string
+	// Symbol before the dynamic type of an interface, default "("
+	DynamicTypeStart string
+	// Symbol written instead of the contents of a value that is omitted,
+	// default "..."
+	Elision string
+	// Flag indicating whether Error() string method takes precedence over
+	// String() string method if the data type supports both, default false
+	ErrorBeforeString bool
+	// Flag indicating whether errors wrapped by an error should be written
+	// as well, default false
+	ErrorChain bool
+	// Symbol between an error and the error it wraps, default " -> "
+	ErrorChainSep string
+	// Symbol at the end of a list of errors wrapped by one error, default "]"
+	ErrorListEnd string
+	// Symbol between two errors wrapped by one error, default ", "
+	ErrorListSep string
+	// Symbol at the start of a list of errors wrapped by one error, default "["
+	ErrorListStart string
 	// Maximum number of decimal places to write when processing a floating-point
 	// number, default 3
 	FloatDecimalPlaces int
@@ -32,9 +74,36 @@ type Options struct {
 	// It returns a function of type KeyLessType.
 	// Passing nil will leave keys unsorted.
 	GetLessFunc GetLessType
-	// Flag indicating whether to ignore custom String() string method
-	// if the data type supports it
+	// Path of the package whose types are written unqualified
+	// in Go syntax. Types of package main are always written unqualified,
+	// default ""
+	GoSyntaxPackage string
+	// Flag indicating whether unexported fields of structs are written
+	// as fields in Go syntax instead of comments, default false
+	GoSyntaxUnexported bool
+	// Flag indicating whether to ignore custom String() string
+	// and Error() string methods if the data type supports them
 	IgnoreCustomMethod bool
+	// Symbol between the label or address of a pointer and its target,
+	// default "="
+	LabelSep string
+	// Symbol at the start of a label of a pointer, default "#"
+	LabelStart string
+	// Flag indicating whether 2D and 3D numeric arrays and slices should be
+	// written as LaTeX matrices. Other values are written in the default
+	// format, default false
+	LaTeX bool
+	// Environment of a LaTeX matrix, such as "bmatrix" or "pmatrix",
+	// default "bmatrix"
+	LaTeXMatrix string
+	// Symbol at the end of an annotation written by ShowLen, default ")"
+	LenEnd string
+	// Symbol at the start of an annotation written by ShowLen, default "(len="
+	LenStart string
+	// Maximum width of a line. Structs, maps and arrays that don't fit
+	// are broken into indented lines, others stay on one line.
+	// Ignored in pretty mode. Zero means no limit, default 0
+	LineWidth int
 	// Symbol at the start of a map, default "}"
 	MapEnd string
 	// Symbol between key and value of a map, default ":"
@@ -43,11 +112,57 @@ type Options struct {
 	MapSepVal string
 	// Symbol at the start of a map, default "{"
 	MapStart string
+	// Flag indicating whether 2D arrays and slices and arrays or slices
+	// of structs should be written as a markdown table. Other values are
+	// written in a fenced code block, default false
+	Markdown bool
+	// Composite values nested at least MaxDepth levels deep are replaced
+	// by Elision. Pointers count as a level. Zero means no limit, default 0
+	MaxDepth int
+	// Arrays and slices with more elements in a single dimension are
+	// summarized by their first SummaryHead and last SummaryTail elements.
+	// Zero means no limit, default 0
+	MaxElements int
+	// Maps with more key-value pairs are summarized by their first
+	// SummaryHead and last SummaryTail pairs. Zero means no limit, default 0
+	MaxEntries int
+	// Flag indicating whether 2D and 3D numeric arrays and slices should be
+	// written as a NumPy array literal. Other values are written
+	// in the default format, default false
+	NumPy bool
+	// Flag indicating whether pointers should be written with their address,
+	// like 0xc000012345=&{1 2}, default false
+	PointerAddresses bool
+	// Flag indicating whether pointers referenced more than once should be
+	// labeled. The first occurrence is written like #1=&{1 2}, later ones
+	// and cycles as #1, default false
+	PointerLabels bool
+	// Flag indicating whether custom methods declared on a pointer receiver
+	// should be called on values that aren't stored as pointers, default false
+	PointerMethods bool
+	// Flag indicating whether each field of a struct and each key-value pair
+	// of a map should be written on its own indented line, default false
+	Pretty bool
+	// Indentation symbol used in pretty mode and by lines broken
+	// to fit LineWidth. It replaces ArrayIndent in layers
+	// of multidimensional arrays as well, default 4 spaces
+	PrettyIndent string
+	// Symbol written instead of the value of a struct field
+	// tagged with redact, default "***"
+	RedactedValue string
 	// Flag indicating whether a rune array or slice should be written
 	// as a string, default false
 	RuneAsString bool
+	// Flag indicating whether to write the dynamic type of an interface
+	// after its value, default false
+	ShowDynamicType bool
 	// Flag indicating whether to write a name of each field of a struct
 	ShowFieldNames bool
+	// Flag indicating whether to annotate arrays, slices, maps, strings
+	// and channels with their length. Slices and channels are annotated
+	// with their capacity as well. Annotations replace summaries of lengths,
+	// other output formats ignore them, default false
+	ShowLen bool
 	// Flag indicating whether to write a type name before the final string,
 	// default false
 	ShowType bool
@@ -59,9 +174,41 @@ type Options struct {
 	StructSepFieldValue string
 	// Symbol at the start of a struct, default "{"
 	StructStart string
+	// Symbol after the length of a summarized collection, default ")"
+	SummaryEnd string
+	// Number of elements written before the elision
+	// of a summarized collection, negative counts as 0, default 3
+	SummaryHead int
+	// Symbol before the length of a summarized collection, default " (len="
+	SummaryStart string
+	// Number of elements written after the elision
+	// of a summarized collection, negative counts as 0, default 3
+	SummaryTail int
+	// Flag indicating whether 2D arrays and slices and arrays, slices or maps
+	// of structs should be written as a table with borders, default false
+	Table bool
+	// Flag indicating whether the first column of a table holds indices
+	// of rows. Keys of a map are always written, default false
+	TableRowIndices bool
+	// Flag indicating whether borders of a table are drawn by Unicode
+	// box-drawing characters instead of ASCII, default false
+	TableUnicode bool
+	// Key of struct tags that control how fields are written.
+	// Tag options: a new name, "-" to skip the field, "omitempty"
+	// to skip a zero value and "redact" to hide the value.
+	// "attr" writes the field as an attribute in XML.
+	// Empty string ignores tags, default "anystring"
+	TagName string
+	// Output format with its own symbols, set by the ConvertTo function
+	// of the format
+	format uint
+	// Custom formatters registered by type
+	formatters map[r.Type]FormatterType
 }
 
 const (
+	// Default flag indicating whether to align columns of 2D layers
+	DefaultAlignColumns bool = false
 	// Default symbol at the end of an array or slice
 	DefaultArrayEnd string = "]"
 	// Default indentation symbol used to indent layers in multidimensional array or slice
@@ -76,6 +223,31 @@ const (
 	DefaultArrayStart string = "["
 	// Default flag indicating whether a byte array or slice should be written as a string
 	DefaultByteAsString bool = false
+	// Default symbol between the length and capacity of an annotation
+	DefaultCapSep string = " cap="
+	// Default symbol at the end of a cycle marker
+	DefaultCycleEnd string = ">"
+	// Default symbol at the start of a cycle marker
+	DefaultCycleStart string = "<cycle "
+	// Default symbol after the dynamic type of an interface
+	DefaultDynamicTypeEnd string = ")"
+	// Default symbol before the dynamic type of an interface
+	DefaultDynamicTypeStart string = "("
+	// Default symbol written instead of the contents of an omitted value
+	DefaultElision string = "..."
+	// Default flag indicating whether Error() string method takes precedence
+	// over String() string method
+	DefaultErrorBeforeString bool = false
+	// Default flag indicating whether errors wrapped by an error should be written
+	DefaultErrorChain bool = false
+	// Default symbol between an error and the error it wraps
+	DefaultErrorChainSep string = " -> "
+	// Default symbol at the end of a list of errors wrapped by one error
+	DefaultErrorListEnd string = "]"
+	// Default symbol between two errors wrapped by one error
+	DefaultErrorListSep string = ", "
+	// Default symbol at the start of a list of errors wrapped by one error
+	DefaultErrorListStart string = "["
 	// Default maximum number of decimal places to write when processing a floating-point number
 	DefaultFloatDecimalPlaces int = 3
 	// Default symbol at the end of a function's parameter list
@@ -86,9 +258,27 @@ const (
 	DefaultFuncSepInOut string = " "
 	// Default symbol at the start of a function's parameter list
 	DefaultFuncStart string = "("
-	// Default flag indicating whether to ignore custom String() string method
-	// if the data type supports it
+	// Default path of the package whose types are written unqualified
+	DefaultGoSyntaxPackage string = ""
+	// Default flag indicating whether to write unexported fields in Go syntax
+	DefaultGoSyntaxUnexported bool = false
+	// Default flag indicating whether to ignore custom String() string
+	// and Error() string methods if the data type supports them
 	DefaultIgnoreCustomMethod bool = false
+	// Default symbol between the label or address of a pointer and its target
+	DefaultLabelSep string = "="
+	// Default symbol at the start of a label of a pointer
+	DefaultLabelStart string = "#"
+	// Default flag indicating whether to write LaTeX matrices
+	DefaultLaTeX bool = false
+	// Default environment of a LaTeX matrix
+	DefaultLaTeXMatrix string = "bmatrix"
+	// Default symbol at the end of an annotation of a length
+	DefaultLenEnd string = ")"
+	// Default symbol at the start of an annotation of a length
+	DefaultLenStart string = "(len="
+	// Default maximum width of a line, no limit
+	DefaultLineWidth int = 0
 	// Default symbol at the end of a map
 	DefaultMapEnd string = "}"
 	// Default symbol between key and value of a map
@@ -97,10 +287,37 @@ const (
 	DefaultMapSepVal string = " "
 	// Default symbol at the start of a map
 	DefaultMapStart string = "{"
+	// Default flag indicating whether to write a markdown table
+	DefaultMarkdown bool = false
+	// Default maximum depth of nested composite values, no limit
+	DefaultMaxDepth int = 0
+	// Default maximum number of elements in a single dimension, no limit
+	DefaultMaxElements int = 0
+	// Default maximum number of key-value pairs of a map, no limit
+	DefaultMaxEntries int = 0
+	// Default flag indicating whether to write a NumPy array literal
+	DefaultNumPy bool = false
+	// Default flag indicating whether to write addresses of pointers
+	DefaultPointerAddresses bool = false
+	// Default flag indicating whether to label pointers referenced more than once
+	DefaultPointerLabels bool = false
+	// Default flag indicating whether custom methods declared on a pointer
+	// receiver should be called on values that aren't stored as pointers
+	DefaultPointerMethods bool = false
+	// Default flag indicating whether to write structs and maps on multiple lines
+	DefaultPretty bool = false
+	// Default indentation symbol used in pretty mode
+	DefaultPrettyIndent string = "    "
+	// Default symbol written instead of the value of a redacted field
+	DefaultRedactedValue string = "***"
 	// Default flag indicating whether a rune array or slice should be written as a string
 	DefaultRuneAsString bool = false
+	// Default flag indicating whether to write the dynamic type of an interface
+	DefaultShowDynamicType bool = false
 	// Default flag indicating whether to write a name of each field of a struct
 	DefaultShowFieldNames bool = false
+	// Default flag indicating whether to annotate lengths and capacities
+	DefaultShowLen bool = false
 	// Default flag indicating whether to write a type name before the final string
 	DefaultShowType bool = false
 	// Default symbol at the end of a struct
@@ -111,11 +328,29 @@ const (
 	DefaultStructSepFieldValue string = " "
 	// Default symbol at the start of a struct
 	DefaultStructStart string = "{"
+	// Default symbol after the length of a summarized collection
+	DefaultSummaryEnd string = ")"
+	// Default number of elements written before the elision
+	DefaultSummaryHead int = 3
+	// Default symbol before the length of a summarized collection
+	DefaultSummaryStart string = " (len="
+	// Default number of elements written after the elision
+	DefaultSummaryTail int = 3
+	// Default flag indicating whether to write a table with borders
+	DefaultTable bool = false
+	// Default flag indicating whether to write indices of rows of a table
+	DefaultTableRowIndices bool = false
+	// Default flag indicating whether to draw borders of a table by Unicode characters
+	DefaultTableUnicode bool = false
+	// Default key of struct tags that control how fields are written
+	DefaultTagName string = "anystring"
 )
 
 // Constructs new Options with default values
 func NewOptions() *Options {
 	return &Options{
+		AlignColumns:        DefaultAlignColumns,
+		ArrayDimensions:     nil,
 		ArrayEnd:            DefaultArrayEnd,
 		ArrayIndent:         DefaultArrayIndent,
 		ArraySep:            DefaultArraySep,
@@ -123,23 +358,92 @@ func NewOptions() *Options {
 		ArraySep3D:          DefaultArraySep3D,
 		ArrayStart:          DefaultArrayStart,
 		ByteAsString:        DefaultByteAsString,
+		CapSep:              DefaultCapSep,
+		CycleEnd:            DefaultCycleEnd,
+		CycleStart:          DefaultCycleStart,
+		DynamicTypeEnd:      DefaultDynamicTypeEnd,
+		DynamicTypeStart:    DefaultDynamicTypeStart,
+		Elision:             DefaultElision,
+		ErrorBeforeString:   DefaultErrorBeforeString,
+		ErrorChain:          DefaultErrorChain,
+		ErrorChainSep:       DefaultErrorChainSep,
+		ErrorListEnd:        DefaultErrorListEnd,
+		ErrorListSep:        DefaultErrorListSep,
+		ErrorListStart:      DefaultErrorListStart,
 		FloatDecimalPlaces:  DefaultFloatDecimalPlaces,
 		FuncEnd:             DefaultFuncEnd,
 		FuncSep:             DefaultFuncSep,
 		FuncSepInOut:        DefaultFuncSepInOut,
 		FuncStart:           DefaultFuncStart,
 		GetLessFunc:         DefaultGetLess,
+		GoSyntaxPackage:     DefaultGoSyntaxPackage,
+		GoSyntaxUnexported:  DefaultGoSyntaxUnexported,
 		IgnoreCustomMethod:  DefaultIgnoreCustomMethod,
+		LabelSep:            DefaultLabelSep,
+		LabelStart:          DefaultLabelStart,
+		LaTeX:               DefaultLaTeX,
+		LaTeXMatrix:         DefaultLaTeXMatrix,
+		LenEnd:              DefaultLenEnd,
+		LenStart:            DefaultLenStart,
+		LineWidth:           DefaultLineWidth,
 		MapEnd:              DefaultMapEnd,
 		MapSepKey:           DefaultMapSepKey,
 		MapSepVal:           DefaultMapSepVal,
 		MapStart:            DefaultMapStart,
+		Markdown:            DefaultMarkdown,
+		MaxDepth:            DefaultMaxDepth,
+		MaxElements:         DefaultMaxElements,
+		MaxEntries:          DefaultMaxEntries,
+		NumPy:               DefaultNumPy,
+		PointerAddresses:    DefaultPointerAddresses,
+		PointerLabels:       DefaultPointerLabels,
+		PointerMethods:      DefaultPointerMethods,
+		Pretty:              DefaultPretty,
+		PrettyIndent:        DefaultPrettyIndent,
+		RedactedValue:       DefaultRedactedValue,
 		RuneAsString:        DefaultRuneAsString,
+		ShowDynamicType:     DefaultShowDynamicType,
 		ShowFieldNames:      DefaultShowFieldNames,
+		ShowLen:             DefaultShowLen,
 		ShowType:            DefaultShowType,
 		StructEnd:           DefaultStructEnd,
 		StructSepFieldName:  DefaultStructSepFieldName,
 		StructSepFieldValue: DefaultStructSepFieldValue,
 		StructStart:         DefaultStructStart,
+		SummaryEnd:          DefaultSummaryEnd,
+		SummaryHead:         DefaultSummaryHead,
+		SummaryStart:        DefaultSummaryStart,
+		SummaryTail:         DefaultSummaryTail,
+		Table:               DefaultTable,
+		TableRowIndices:     DefaultTableRowIndices,
+		TableUnicode:        DefaultTableUnicode,
+		TagName:             DefaultTagName,
+	}
+}
+
+// Returns the formatter registered for the type
+func (o *Options) formatter(aType r.Type) (FormatterType, bool) {
+	f, ok := o.formatters[aType]
+	return f, ok
+}
+
+// Function type that converts a Value to a string
+type FormatterType = func(r.Value) string
+
+// Registers a custom formatter for values of given type.
+// The formatter takes precedence over custom methods
+// and applies at any depth.
+func (o *Options) RegisterFormatter(aType r.Type, f FormatterType) {
+	if o.formatters == nil {
+		o.formatters = map[r.Type]FormatterType{}
 	}
+
+	o.formatters[aType] = f
+}
+
+// Registers a custom formatter for values of type T
+func RegisterFormatterFor[T any](o *Options, f func(T) string) {
+	o.RegisterFormatter(r.TypeOf((*T)(nil)).Elem(), func(val r.Value) string {
+		return f(val.Interface().(T))
+	})
 }
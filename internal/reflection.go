@@ -3,18 +3,53 @@ package internal
 import (
 	r "reflect"
 	"strings"
+	"unsafe"
 
 	gs "github.com/Matej-Chmel/go-generic-stack"
 )
 
+// Returns the contents of an array or slice of bytes or runes as a string
+func bytesToString(val *r.Value) string {
+	var builder strings.Builder
+	isByte := val.Type().Elem().Kind() == r.Uint8
+
+	for i := 0; i < val.Len(); i++ {
+		if elem := val.Index(i); isByte {
+			builder.WriteByte(byte(elem.Uint()))
+		} else {
+			builder.WriteRune(rune(elem.Int()))
+		}
+	}
+
+	return builder.String()
+}
+
+// Calls a method with signature func() string.
+// Returns false if the method is invalid or has a different signature.
+func callStringMethod(method r.Value) (string, bool) {
+	if !method.IsValid() {
+		return "", false
+	}
+
+	if t := method.Type(); t.NumIn() != 0 || t.NumOut() != 1 ||
+		t.Out(0).Kind() != r.String {
+		return "", false
+	}
+
+	return method.Call(nil)[0].String(), true
+}
+
 // Counts the number of dimensions of an array or a slice
 func countDimensions(val *r.Value) (d uint32) {
 	t := val.Type()
+	seen := map[r.Type]bool{}
 
 	for {
 		kind := t.Kind()
 
-		if kind == r.Array || kind == r.Slice {
+		if (kind == r.Array || kind == r.Slice) && !seen[t] {
+			// Recursive types like type S []S count only once
+			seen[t] = true
 			d++
 			t = t.Elem()
 		} else {
@@ -65,6 +100,26 @@ func formatCompositeType(val *r.Value) string {
 	return builder.String()
 }
 
+// Returns the name of the type of a Value that forms a cycle,
+// pointers are prefixed with &
+func formatCycleType(val *r.Value) string {
+	if val.Kind() == r.Pointer {
+		return "&" + formatTypeName(val.Type().Elem())
+	}
+
+	return formatTypeName(val.Type())
+}
+
+// Returns the name of a named type or the full description
+// of an unnamed type
+func formatTypeName(aType r.Type) string {
+	if name := aType.Name(); name != "" {
+		return name
+	}
+
+	return aType.String()
+}
+
 // Returns the name of the type of the given Value
 func FormatType(val *r.Value) string {
 	if IsCompositeType(val) {
@@ -79,6 +134,9 @@ func IsCompositeType(val *r.Value) bool {
 	switch val.Kind() {
 	case r.Array, r.Map, r.Pointer, r.Slice, r.Struct:
 		return true
+	case r.Interface:
+		// Non-nil interfaces are unwrapped by the CompositeConverter
+		return !val.IsNil()
 	}
 
 	return false
@@ -96,6 +154,64 @@ func IsNil(val *r.Value) bool {
 	return false
 }
 
+// Identity of a pointer, map or slice
+type reference struct {
+	addr  uintptr
+	aType r.Type
+}
+
+// Constructs a reference for a non-empty pointer, map or slice.
+// Returns false if the Value can't form a cycle.
+func newReference(val *r.Value) (reference, bool) {
+	switch val.Kind() {
+	case r.Map, r.Pointer:
+		if val.IsNil() {
+			return reference{}, false
+		}
+	case r.Slice:
+		if val.Len() == 0 {
+			return reference{}, false
+		}
+	default:
+		return reference{}, false
+	}
+
+	return reference{addr: val.Pointer(), aType: val.Type()}, true
+}
+
+// Returns the i-th field of an addressable struct. Values of unexported
+// fields are retrieved from their memory address.
+func structField(val *r.Value, i int) r.Value {
+	field := val.Field(i)
+
+	if field.CanInterface() {
+		return field
+	}
+
+	addr := unsafe.Pointer(field.UnsafeAddr())
+	return r.NewAt(field.Type(), addr).Elem()
+}
+
+// Unwraps interfaces and pointers of a value
+// unless they're nil or form a cycle
+func unwrapValue(val r.Value) r.Value {
+	visited := map[uintptr]bool{}
+
+	for (val.Kind() == r.Interface || val.Kind() == r.Pointer) && !val.IsNil() {
+		if val.Kind() == r.Pointer {
+			if visited[val.Pointer()] {
+				break
+			}
+
+			visited[val.Pointer()] = true
+		}
+
+		val = val.Elem()
+	}
+
+	return val
+}
+
 // Internal struct for a Type in a stack
 type typeInfo struct {
 	aType  r.Type
@@ -0,0 +1,421 @@
+package internal
+
+import (
+	r "reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Converts a 2D array or slice or an array, slice or map of structs
+// to a table with borders. Columns of numbers are aligned to the right.
+// Empty slices of structs are written as a header only, other arrays
+// and slices without cells as []. Returns false if the value isn't tabular.
+func ConvertToTable(o *Options, val *r.Value) (string, bool) {
+	t, ok := newTable(newCellOptions(o), val, o.TableRowIndices)
+
+	if !ok {
+		if hasNoCells(val) {
+			return o.ArrayStart + o.ArrayEnd, true
+		}
+
+		return "", false
+	}
+
+	border := &asciiBorder
+
+	if o.TableUnicode {
+		border = &unicodeBorder
+	}
+
+	widths := t.widths()
+	numeric := make([]bool, len(t.header))
+
+	for i := range numeric {
+		numeric[i] = t.isNumeric(i)
+	}
+
+	var builder strings.Builder
+	border.writeLine(&builder, widths, 0)
+	border.writeRow(&builder, widths, numeric, t.header)
+	border.writeLine(&builder, widths, 1)
+
+	for _, row := range t.rows {
+		border.writeRow(&builder, widths, numeric, t.rowTexts(row))
+	}
+
+	border.writeLine(&builder, widths, 2)
+	return strings.TrimSuffix(builder.String(), "\n"), true
+}
+
+// Table of cells converted from a 2D array or slice
+// or from an array, slice or map of structs
+type table struct {
+	// Names of the columns
+	header []string
+	// Rows of cells, a row may be shorter than the header
+	rows [][]tableCell
+}
+
+// Constructs a table from a value. If labels is true, the first column
+// holds indices of rows. Keys of a map are always written in the first
+// column. Returns false if the value isn't tabular or has no columns.
+func newTable(o *Options, val *r.Value, labels bool) (table, bool) {
+	elem := unwrapValue(*val)
+	var res table
+	var rows []r.Value
+	var rowLabels []tableCell
+
+	switch elem.Kind() {
+	case r.Array, r.Slice:
+		for i := 0; i < elem.Len(); i++ {
+			rows = append(rows, elem.Index(i))
+			rowLabels = append(rowLabels, tableCell{
+				number: true,
+				text:   strconv.Itoa(i),
+			})
+		}
+	case r.Map:
+		keys := elem.MapKeys()
+
+		if o.GetLessFunc != nil {
+			SortKeys(keys, o.GetLessFunc)
+		}
+
+		for _, key := range keys {
+			rows = append(rows, elem.MapIndex(key))
+			rowLabels = append(rowLabels, newTableCell(o, key))
+		}
+
+		// Keys identify the rows
+		labels = true
+	default:
+		return res, false
+	}
+
+	rowType := elem.Type().Elem()
+
+	if rowType.Kind() == r.Pointer {
+		rowType = rowType.Elem()
+	}
+
+	if elem.Kind() != r.Map && countDimensions(&elem) == 2 {
+		res = newArrayTable(o, rows)
+	} else if rowType.Kind() == r.Struct {
+		res = newStructTable(o, rows, rowType)
+	}
+
+	if len(res.header) == 0 {
+		return res, false
+	}
+
+	if labels {
+		res.header = append([]string{""}, res.header...)
+
+		for i, label := range rowLabels {
+			res.rows[i] = append([]tableCell{label}, res.rows[i]...)
+		}
+	}
+
+	return res, true
+}
+
+// Returns true if the value is an array or slice without cells,
+// either empty or with empty rows only
+func hasNoCells(val *r.Value) bool {
+	elem := unwrapValue(*val)
+
+	if elem.Kind() != r.Array && elem.Kind() != r.Slice {
+		return false
+	}
+
+	for i := 0; i < elem.Len(); i++ {
+		row := unwrapValue(elem.Index(i))
+
+		if (row.Kind() != r.Array && row.Kind() != r.Slice) || row.Len() > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns true if all non-empty cells of a column are numbers
+// and at least one of them isn't empty
+func (t *table) isNumeric(column int) bool {
+	res := false
+
+	for _, row := range t.rows {
+		if column >= len(row) || row[column].text == "" {
+			continue
+		}
+
+		if !row[column].number {
+			return false
+		}
+
+		res = true
+	}
+
+	return res
+}
+
+// Returns texts of cells of a row, missing cells are empty
+func (t *table) rowTexts(row []tableCell) []string {
+	res := make([]string, len(t.header))
+
+	for i, cell := range row {
+		res[i] = cell.text
+	}
+
+	return res
+}
+
+// Returns the width of each column, the widest line of its cells
+func (t *table) widths() []int {
+	res := make([]int, len(t.header))
+
+	for i, name := range t.header {
+		res[i] = textWidth(name)
+	}
+
+	for _, row := range t.rows {
+		for i, cell := range row {
+			for _, line := range strings.Split(cell.text, "\n") {
+				res[i] = max(res[i], textWidth(line))
+			}
+		}
+	}
+
+	return res
+}
+
+// Constructs a table from rows that are arrays or slices.
+// Columns are named by their indices.
+func newArrayTable(o *Options, rows []r.Value) table {
+	res := table{header: []string{}, rows: make([][]tableCell, len(rows))}
+
+	for i, row := range rows {
+		res.rows[i] = make([]tableCell, row.Len())
+
+		for j := range res.rows[i] {
+			res.rows[i][j] = newTableCell(o, row.Index(j))
+		}
+
+		for j := len(res.header); j < row.Len(); j++ {
+			res.header = append(res.header, strconv.Itoa(j))
+		}
+	}
+
+	return res
+}
+
+// Constructs a table from rows that are structs or pointers to structs.
+// Columns are named by fields, nil pointers are empty rows.
+func newStructTable(o *Options, rows []r.Value, rowType r.Type) table {
+	res := table{header: []string{}, rows: make([][]tableCell, len(rows))}
+	var tags []fieldTag
+
+	for i := 0; i < rowType.NumField(); i++ {
+		tag := newFieldTag(rowType.Field(i), o.TagName)
+		tags = append(tags, tag)
+
+		if !tag.omit {
+			res.header = append(res.header, tag.name)
+		}
+	}
+
+	for i, row := range rows {
+		if row = unwrapValue(row); row.Kind() != r.Struct {
+			continue
+		}
+
+		// Create pointer to the struct like convertStruct does
+		tmp := r.New(rowType)
+		tmp.Elem().Set(row)
+		row = tmp.Elem()
+
+		for j, tag := range tags {
+			field := structField(&row, j)
+			var cell tableCell
+
+			if tag.omit {
+				continue
+			} else if tag.redact {
+				cell.text = o.RedactedValue
+			} else if !tag.omitEmpty || !field.IsZero() {
+				cell = newTableCell(o, field)
+			}
+
+			res.rows[i] = append(res.rows[i], cell)
+		}
+	}
+
+	return res
+}
+
+// Symbols that draw borders of a table
+type tableBorder struct {
+	horizontal string
+	// Corners and junctions of the top, middle and bottom line,
+	// each from left to right
+	junctions [3][3]string
+	vertical  string
+}
+
+var (
+	// Borders drawn by ASCII characters
+	asciiBorder = tableBorder{
+		horizontal: "-",
+		junctions:  [3][3]string{{"+", "+", "+"}, {"+", "+", "+"}, {"+", "+", "+"}},
+		vertical:   "|",
+	}
+	// Borders drawn by Unicode box-drawing characters
+	unicodeBorder = tableBorder{
+		horizontal: "─",
+		junctions:  [3][3]string{{"┌", "┬", "┐"}, {"├", "┼", "┤"}, {"└", "┴", "┘"}},
+		vertical:   "│",
+	}
+)
+
+// Write a horizontal line of the border. Position 0 is the top line,
+// 1 the line below the header and 2 the bottom line.
+func (b *tableBorder) writeLine(builder *strings.Builder, widths []int, position int) {
+	junctions := b.junctions[position]
+	builder.WriteString(junctions[0])
+
+	for i, width := range widths {
+		if i > 0 {
+			builder.WriteString(junctions[1])
+		}
+
+		builder.WriteString(strings.Repeat(b.horizontal, width+2))
+	}
+
+	builder.WriteString(junctions[2])
+	builder.WriteByte('\n')
+}
+
+// Write a row of cells padded to the widths of their columns.
+// Cells with multiple lines make the row higher, numbers are aligned
+// to the right.
+func (b *tableBorder) writeRow(
+	builder *strings.Builder, widths []int, numeric []bool, cells []string,
+) {
+	lines := make([][]string, len(cells))
+	height := 1
+
+	for i, cell := range cells {
+		lines[i] = strings.Split(cell, "\n")
+		height = max(height, len(lines[i]))
+	}
+
+	for l := 0; l < height; l++ {
+		builder.WriteString(b.vertical)
+
+		for i, cellLines := range lines {
+			text := ""
+
+			if l < len(cellLines) {
+				text = cellLines[l]
+			}
+
+			padding := strings.Repeat(" ", widths[i]-textWidth(text))
+			builder.WriteByte(' ')
+
+			if numeric[i] {
+				builder.WriteString(padding + text)
+			} else {
+				builder.WriteString(text + padding)
+			}
+
+			builder.WriteByte(' ')
+			builder.WriteString(b.vertical)
+		}
+
+		builder.WriteByte('\n')
+	}
+}
+
+// Cell of a table
+type tableCell struct {
+	// Flag indicating whether the cell holds a number
+	number bool
+	text   string
+}
+
+// Converts a value to a cell. Basic values are converted
+// by the LeafConverter, composite values by the CompositeConverter.
+func newTableCell(o *Options, val r.Value) tableCell {
+	if val.Kind() == r.Interface && !val.IsNil() {
+		val = val.Elem()
+	}
+
+	var res tableCell
+
+	if IsCompositeType(&val) {
+		c := NewCompositeConverter(o, &val)
+		res.text = c.ConvertStackToString()
+	} else {
+		c := NewLeafConverter(o)
+		res.text = c.ConvertToString(&val)
+	}
+
+	switch val.Kind() {
+	case r.Float32, r.Float64, r.Int, r.Int8, r.Int16, r.Int64,
+		r.Uint, r.Uint16, r.Uint32, r.Uint64, r.Uintptr:
+		res.number = true
+	case r.Int32:
+		// Runes may be written as characters
+		res.number = !o.RuneAsString
+	case r.Uint8:
+		res.number = !o.ByteAsString
+	}
+
+	return res
+}
+
+// Characters that take two columns in a terminal,
+// East Asian wide and fullwidth characters and emoji
+var wideChars = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115F, Stride: 1},
+		{Lo: 0x231A, Hi: 0x231B, Stride: 1},
+		{Lo: 0x2E80, Hi: 0x303E, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33FF, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4DBF, Stride: 1},
+		{Lo: 0x4E00, Hi: 0x9FFF, Stride: 1},
+		{Lo: 0xA000, Hi: 0xA4CF, Stride: 1},
+		{Lo: 0xA960, Hi: 0xA97F, Stride: 1},
+		{Lo: 0xAC00, Hi: 0xD7A3, Stride: 1},
+		{Lo: 0xF900, Hi: 0xFAFF, Stride: 1},
+		{Lo: 0xFE10, Hi: 0xFE19, Stride: 1},
+		{Lo: 0xFE30, Hi: 0xFE6F, Stride: 1},
+		{Lo: 0xFF00, Hi: 0xFF60, Stride: 1},
+		{Lo: 0xFFE0, Hi: 0xFFE6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1F300, Hi: 0x1F64F, Stride: 1},
+		{Lo: 0x1F900, Hi: 0x1F9FF, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2FFFD, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3FFFD, Stride: 1},
+	},
+}
+
+// Returns the number of columns a string takes in a terminal. Wide
+// characters take two columns, combining marks and format characters none.
+func textWidth(s string) int {
+	res := 0
+
+	for _, char := range s {
+		switch {
+		case unicode.In(char, unicode.Mn, unicode.Me, unicode.Cf):
+		case unicode.Is(wideChars, char):
+			res += 2
+		default:
+			res++
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	r "reflect"
+	"strings"
+)
+
+// Rendering options of a struct field read from its tag, for example
+// `anystring:"name,omitempty"`, `anystring:"-"` or `anystring:",redact"`
+type fieldTag struct {
+	// The field is written as an attribute of an XML element
+	attr bool
+	// Name of the field to write if field names are shown
+	name string
+	// The field is never written
+	omit bool
+	// The field isn't written if it holds a zero value
+	omitEmpty bool
+	// The value of the field is replaced by a mask
+	redact bool
+}
+
+// Reads the tag of given key from a struct field.
+// Passing an empty key ignores all tags.
+func newFieldTag(field r.StructField, key string) fieldTag {
+	tag := fieldTag{name: field.Name}
+
+	if key == "" {
+		return tag
+	}
+
+	value, ok := field.Tag.Lookup(key)
+
+	if !ok {
+		return tag
+	}
+
+	if value == "-" {
+		tag.omit = true
+		return tag
+	}
+
+	parts := strings.Split(value, ",")
+
+	if parts[0] != "" {
+		tag.name = parts[0]
+	}
+
+	for _, option := range parts[1:] {
+		switch strings.TrimSpace(option) {
+		case "attr":
+			tag.attr = true
+		case "omitempty":
+			tag.omitEmpty = true
+		case "redact":
+			tag.redact = true
+		}
+	}
+
+	return tag
+}
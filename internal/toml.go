@@ -0,0 +1,519 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	r "reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Value of a key/value pair is written inline
+	tomlInline uint = iota
+	// Value can't be written in TOML and its key/value pair is skipped
+	tomlSkip
+	// Value is a struct or map written as a table with its own header
+	tomlTable
+	// Value is an array or slice of structs or maps written
+	// as an array of tables
+	tomlTables
+)
+
+// Entry of a struct or map written as a TOML table
+type tomlEntry struct {
+	// Key formatted as a bare or quoted key
+	name string
+	// Flag indicating whether the value should be redacted
+	redact bool
+	val    r.Value
+}
+
+// Value of an entry with interfaces and pointers unwrapped
+type tomlValue struct {
+	// How the value is written
+	kind uint
+	// Description of the value if it can't be written
+	reason string
+	// References of the unwrapped pointers and the value itself
+	refs []reference
+	val  r.Value
+}
+
+// Converts a struct or map to a TOML document. Values that can't
+// be written in TOML are skipped and reported in the returned error.
+func ConvertToTOML(o *Options, val *r.Value) (string, error) {
+	c := NewCompositeConverter(withFormat(o, formatTOML), val)
+	return c.ConvertStackToString(), c.Err()
+}
+
+// Adds the reference of the value to the references of a tomlValue.
+// Returns false if the value contains itself.
+func (c *CompositeConverter) addTOMLReference(value *tomlValue) bool {
+	ref, ok := newReference(&value.val)
+
+	if !ok {
+		return true
+	}
+
+	if c.visited[ref] {
+		return false
+	}
+
+	value.refs = append(value.refs, ref)
+	return true
+}
+
+// Converts an array or slice. Arrays of tables are written as a header
+// followed by a table for each element, others are written inline.
+// Elements that can't be written are skipped.
+func (c *CompositeConverter) convertTOMLArray(it *Item) {
+	length := it.val.Len()
+
+	if it.flag == Tables {
+		if it.ix == length {
+			c.pop()
+			return
+		}
+
+		elem := c.tomlValueOf(it.val.Index(it.ix))
+		path := fmt.Sprintf("%s[%d]", it.path, it.ix)
+		it.ix++
+
+		if elem.kind != tomlTable {
+			c.errs = append(c.errs, fmt.Errorf(
+				"%s: %s can't be written in TOML", path, elem.reason))
+			return
+		}
+
+		c.writeTOMLLine(true)
+		c.write("[[" + it.key + "]]")
+		c.pushTOMLTable(it.key, path, &elem)
+		return
+	}
+
+	if it.ix == 0 {
+		if it.val.Kind() == r.Slice && c.convertCycle(it) {
+			return
+		}
+
+		if c.isString(it.val) {
+			c.write(jsonQuote(bytesToString(it.val)))
+			c.pop()
+			return
+		}
+
+		c.write(c.options.ArrayStart)
+	}
+
+	for ; it.ix < length; it.ix++ {
+		elem := it.val.Index(it.ix)
+
+		if c.tomlSkipped(it, fmt.Sprintf("[%d]", it.ix), &elem) {
+			continue
+		}
+
+		if it.count > 0 {
+			c.write(c.options.ArraySep)
+		}
+
+		c.push(None, 0, &elem).path = fmt.Sprintf("%s[%d]", it.path, it.ix)
+		it.count++
+		it.ix++
+		return
+	}
+
+	c.write(c.options.ArrayEnd)
+	c.pop()
+}
+
+// If Item it is a TOML table or an array of tables,
+// converts it and returns true
+func (c *CompositeConverter) convertTOMLFlagged(it *Item) bool {
+	switch it.flag {
+	case TableSections, TableValues:
+		c.convertTOMLTable(it)
+	case Tables:
+		c.convertTOMLArray(it)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// Writes a value of a basic type as a TOML value. Values that have
+// no TOML equivalent are written as strings that describe them.
+func (c *CompositeConverter) convertTOMLLeaf(it *Item) {
+	val := it.val
+
+	switch val.Kind() {
+	case r.Bool:
+		c.write(c.formatBool(val))
+	case r.Float32, r.Float64:
+		c.write(c.formatTOMLFloat(val))
+	case r.Int, r.Int8, r.Int16, r.Int32, r.Int64:
+		c.write(c.formatInt(val))
+	case r.String:
+		c.write(jsonQuote(val.String()))
+	case r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64:
+		c.write(c.formatUint(val))
+	case r.Uintptr:
+		c.write(c.formatUintptr(val))
+	default:
+		c.write(jsonQuote(c.ConvertToString(val)))
+	}
+}
+
+// Converts a struct or map as a TOML table. Key/value pairs with inline
+// values are written first, tables and arrays of tables follow.
+func (c *CompositeConverter) convertTOMLTable(it *Item) {
+	if it.typ == nil {
+		// First stage, the root must be a table
+		table := c.tomlValueOf(*it.val)
+
+		if table.kind != tomlTable {
+			name := "nil"
+
+			if it.val.IsValid() {
+				name = FormatType(it.val)
+			}
+
+			c.errs = append(c.errs, fmt.Errorf(
+				"TOML document must be a struct or map, not %s", name))
+			c.pop()
+			return
+		}
+
+		c.registerReferences(it, table.refs)
+		c.prepareTOMLTable(it, table.val)
+	}
+
+	var length int
+
+	if it.typ.Kind() == r.Struct {
+		length = it.val.NumField()
+	} else {
+		length = len(it.keys)
+	}
+
+	for ; it.ix < length; it.ix++ {
+		entry, ok := c.tomlEntryAt(it, it.ix)
+
+		if !ok {
+			continue
+		}
+
+		key := it.key + "." + entry.name
+		path := it.path + "." + entry.name
+
+		if it.key == "" {
+			key = entry.name
+			path = entry.name
+		}
+
+		if entry.redact {
+			if it.flag == TableValues {
+				c.writeTOMLLine(false)
+				c.write(entry.name + c.options.StructSepFieldName)
+				c.write(c.options.RedactedValue)
+			}
+
+			continue
+		}
+
+		value := c.tomlValueOf(entry.val)
+
+		if it.flag == TableValues {
+			switch value.kind {
+			case tomlInline:
+				c.writeTOMLLine(false)
+				c.write(entry.name + c.options.StructSepFieldName)
+				c.push(None, 0, &value.val).path = path
+				it.ix++
+				return
+			case tomlSkip:
+				c.errs = append(c.errs, fmt.Errorf(
+					"%s: %s can't be written in TOML", path, value.reason))
+			}
+
+			continue
+		}
+
+		switch value.kind {
+		case tomlTable:
+			c.writeTOMLLine(true)
+			c.write("[" + key + "]")
+			c.pushTOMLTable(key, path, &value)
+			it.ix++
+			return
+		case tomlTables:
+			newItem := c.push(Tables, 0, &value.val)
+			newItem.key = key
+			newItem.path = path
+			c.registerReferences(newItem, value.refs)
+			it.ix++
+			return
+		}
+	}
+
+	if it.flag == TableValues {
+		// Write tables after all key/value pairs
+		it.flag = TableSections
+		it.ix = 0
+		return
+	}
+
+	c.pop()
+}
+
+// Formats a floating-point number, infinities and NaN
+// are written as TOML special values
+func (c *CompositeConverter) formatTOMLFloat(val *r.Value) string {
+	switch f := val.Float(); {
+	case math.IsNaN(f):
+		return "nan"
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	}
+
+	return c.ConvertToString(val)
+}
+
+// Formats a nil slice or map as an empty array or inline table
+func (c *CompositeConverter) formatTOMLNil(val *r.Value) string {
+	if val.Kind() == r.Map {
+		return c.options.MapStart + c.options.MapEnd
+	}
+
+	return c.options.ArrayStart + c.options.ArrayEnd
+}
+
+// Returns a key of a map as a bare or quoted TOML key.
+// Composite keys are converted to JSON and quoted.
+func (c *CompositeConverter) formatTOMLKey(key *r.Value) string {
+	if key.Kind() == r.Interface && !key.IsNil() {
+		elem := key.Elem()
+		key = &elem
+	}
+
+	if key.Kind() == r.String {
+		return tomlKey(key.String())
+	}
+
+	if !IsCompositeType(key) {
+		return tomlKey(c.ConvertToString(key))
+	}
+
+	o := *c.options
+	o.format = formatJSON
+	sub := NewCompositeConverter(&o, key)
+	return jsonQuote(sub.ConvertStackToString())
+}
+
+// Returns true if a custom method or a formatter writes the value
+// as a string, so that it's written inline
+func (c *CompositeConverter) hasCustomString(val *r.Value) bool {
+	if _, ok := c.options.formatter(val.Type()); ok {
+		return true
+	}
+
+	if c.options.IgnoreCustomMethod {
+		return false
+	}
+
+	return c.findReceiver(val, "Error").IsValid() ||
+		c.findReceiver(val, "String").IsValid()
+}
+
+// Returns true if a non-empty array or slice contains only structs
+// and maps, possibly behind interfaces and pointers
+func (c *CompositeConverter) isTOMLArrayOfTables(val *r.Value) bool {
+	if val.Len() == 0 || c.isString(val) {
+		return false
+	}
+
+	for i := 0; i < val.Len(); i++ {
+		elem := val.Index(i)
+
+		for (elem.Kind() == r.Interface || elem.Kind() == r.Pointer) &&
+			!elem.IsNil() {
+			elem = elem.Elem()
+		}
+
+		if kind := elem.Kind(); kind != r.Struct &&
+			(kind != r.Map || elem.IsNil()) || c.hasCustomString(&elem) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Sets up Item it so that the struct or map val can be written as a table
+func (c *CompositeConverter) prepareTOMLTable(it *Item, val r.Value) {
+	if val.Kind() == r.Struct {
+		// Create pointer to the struct like convertStruct does
+		tmp := r.New(val.Type())
+		tmp.Elem().Set(val)
+		val = tmp.Elem()
+	} else {
+		it.keys = val.MapKeys()
+
+		if c.options.GetLessFunc != nil {
+			SortKeys(it.keys, c.options.GetLessFunc)
+		}
+	}
+
+	it.typ = val.Type()
+	it.val = &val
+}
+
+// Push a table written under a header with given key onto the stack,
+// path locates the table in error messages
+func (c *CompositeConverter) pushTOMLTable(key, path string, table *tomlValue) {
+	newItem := c.push(TableValues, 0, &table.val)
+	newItem.key = key
+	newItem.path = path
+	c.registerReferences(newItem, table.refs)
+	c.prepareTOMLTable(newItem, table.val)
+}
+
+// Returns the entry at index i of a table represented by Item it.
+// Returns false if the entry is omitted.
+func (c *CompositeConverter) tomlEntryAt(it *Item, i int) (tomlEntry, bool) {
+	if it.typ.Kind() != r.Struct {
+		key := it.keys[i]
+		return tomlEntry{
+			name:   c.formatTOMLKey(&key),
+			redact: false,
+			val:    it.val.MapIndex(key),
+		}, true
+	}
+
+	tag := newFieldTag(it.typ.Field(i), c.options.TagName)
+	field := structField(it.val, i)
+
+	if tag.omit || (tag.omitEmpty && field.IsZero()) {
+		return tomlEntry{}, false
+	}
+
+	return tomlEntry{name: tomlKey(tag.name), redact: tag.redact, val: field}, true
+}
+
+// If a value of an entry with given name can't be written in TOML,
+// records an error and returns true
+func (c *CompositeConverter) tomlSkipped(it *Item, name string, val *r.Value) bool {
+	if c.options.format != formatTOML {
+		return false
+	}
+
+	value := c.tomlValueOf(*val)
+
+	if value.kind != tomlSkip {
+		return false
+	}
+
+	path := it.path + name
+
+	if !strings.HasPrefix(name, "[") {
+		path = it.path + "." + name
+	}
+
+	c.errs = append(c.errs, fmt.Errorf(
+		"%s: %s can't be written in TOML", path, value.reason))
+	return true
+}
+
+// Returns a tomlValue that is skipped for given reason
+func (c *CompositeConverter) tomlSkipValue(value tomlValue, reason string) tomlValue {
+	value.kind = tomlSkip
+	value.reason = reason
+	return value
+}
+
+// Unwraps interfaces and pointers of a value
+// and determines how it should be written
+func (c *CompositeConverter) tomlValueOf(val r.Value) tomlValue {
+	res := tomlValue{kind: tomlInline, reason: "", refs: nil, val: val}
+
+	for res.val.Kind() == r.Interface || res.val.Kind() == r.Pointer {
+		if res.val.IsNil() {
+			return c.tomlSkipValue(res, "nil")
+		}
+
+		if res.val.Kind() == r.Pointer && !c.addTOMLReference(&res) {
+			return c.tomlSkipValue(res, "cycle")
+		}
+
+		res.val = res.val.Elem()
+	}
+
+	if res.val.IsValid() && c.hasCustomString(&res.val) {
+		return res
+	}
+
+	switch res.val.Kind() {
+	case r.Chan, r.Complex64, r.Complex128, r.Func, r.Invalid, r.UnsafePointer:
+		return c.tomlSkipValue(res, res.val.Kind().String())
+	case r.Uint, r.Uint64, r.Uintptr:
+		// TOML integers are 64-bit signed
+		if res.val.Uint() > math.MaxInt64 {
+			return c.tomlSkipValue(res, strconv.FormatUint(res.val.Uint(), 10))
+		}
+	case r.Map:
+		// Nil map is written as an empty table
+		if !c.addTOMLReference(&res) {
+			return c.tomlSkipValue(res, "cycle")
+		}
+
+		res.kind = tomlTable
+	case r.Struct:
+		res.kind = tomlTable
+	case r.Array, r.Slice:
+		if !c.isTOMLArrayOfTables(&res.val) {
+			return res
+		}
+
+		if res.val.Kind() == r.Slice && !c.addTOMLReference(&res) {
+			return c.tomlSkipValue(res, "cycle")
+		}
+
+		res.kind = tomlTables
+	}
+
+	return res
+}
+
+// Write a line break before a key/value pair or a header.
+// Headers are separated by an empty line.
+func (c *CompositeConverter) writeTOMLLine(header bool) {
+	if c.builder.Len() == 0 {
+		return
+	}
+
+	c.write("\n")
+
+	if header {
+		c.write("\n")
+	}
+}
+
+// Returns a key that is bare if it contains only letters, digits,
+// underscores and dashes, otherwise it's quoted
+func tomlKey(s string) string {
+	if s == "" {
+		return jsonQuote(s)
+	}
+
+	for _, char := range s {
+		if !(char >= 'A' && char <= 'Z' || char >= 'a' && char <= 'z' ||
+			char >= '0' && char <= '9' || char == '_' || char == '-') {
+			return jsonQuote(s)
+		}
+	}
+
+	return s
+}
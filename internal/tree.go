@@ -0,0 +1,131 @@
+package internal
+
+import (
+	r "reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Connector of an entry followed by other entries of the same parent
+	treeBranch = "├── "
+	// Connector of the last entry of a parent
+	treeLast = "└── "
+	// Prefix of entries nested in an entry followed by other entries
+	treePipe = "│   "
+	// Prefix of entries nested in the last entry of a parent
+	treeSpace = "    "
+)
+
+// Converts a value to a tree with each field, key-value pair
+// and element on its own line
+func ConvertToTree(o *Options, val *r.Value) string {
+	c := NewCompositeConverter(withFormat(o, formatTree), val)
+	return c.ConvertStackToString()
+}
+
+// Converts an array or slice, each element is an entry labeled by its index
+func (c *CompositeConverter) convertTreeArray(it *Item) {
+	length := it.val.Len()
+
+	if it.ix == 0 {
+		if it.val.Kind() == r.Slice && c.convertCycle(it) {
+			return
+		}
+
+		if c.isString(it.val) {
+			c.writeValue(it, bytesToString(it.val))
+			c.pop()
+			return
+		}
+
+		if length == 0 {
+			c.write(c.options.ArrayStart + c.options.ArrayEnd)
+			c.pop()
+			return
+		}
+	}
+
+	if it.ix == length {
+		c.pop()
+		return
+	}
+
+	elem := it.val.Index(it.ix)
+	label := "[" + strconv.Itoa(it.ix) + "]"
+	c.pushTreeEntry(it, label, it.ix == length-1, &elem)
+	it.ix++
+}
+
+// Returns a key of a map converted on its own as a label of an entry
+func (c *CompositeConverter) formatTreeKey(key *r.Value) string {
+	o := *c.options
+	o.format = formatDefault
+	sub := NewCompositeConverter(&o, key)
+	return sub.ConvertStackToString()
+}
+
+// Returns true if no field after the current one is written
+func (c *CompositeConverter) isLastField(it *Item) bool {
+	for i := it.ix + 1; i < it.val.NumField(); i++ {
+		tag := newFieldTag(it.typ.Field(i), c.options.TagName)
+		field := structField(it.val, i)
+
+		if !tag.omit && !(tag.omitEmpty && field.IsZero()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Push a field, key-value pair or element as an entry on its own line.
+// Nested entries are prefixed by a pipe unless the entry is the last one.
+// Basic values are written after the label.
+func (c *CompositeConverter) pushTreeEntry(it *Item, label string, last bool, val *r.Value) {
+	c.writeTreeLine(it, label, last)
+	newItem := c.push(None, 0, val)
+
+	if last {
+		newItem.prefix = it.prefix + treeSpace
+	} else {
+		newItem.prefix = it.prefix + treePipe
+	}
+
+	// Discarded if the value has entries
+	c.pendingSep = ": "
+}
+
+// Write a line with the connector and label of an entry of Item it
+func (c *CompositeConverter) writeTreeLine(it *Item, label string, last bool) {
+	c.pendingSep = ""
+	connector := treeBranch
+	// Prefix of lines of a label with a line break
+	prefix := it.prefix + treePipe
+
+	if last {
+		connector = treeLast
+		prefix = it.prefix + treeSpace
+	}
+
+	if c.builder.Len() > 0 {
+		c.write("\n")
+	}
+
+	c.write(it.prefix + connector + strings.ReplaceAll(label, "\n", "\n"+prefix))
+}
+
+// Write the type of a composite root, its entries follow on next lines.
+// Pointers are written in place of their targets.
+func (c *CompositeConverter) writeTreeRoot(it *Item) {
+	if !IsCompositeType(it.val) {
+		return
+	}
+
+	elem := unwrapValue(*it.val)
+
+	if label := FormatType(&elem); label != "" {
+		c.write(label)
+		c.pendingSep = " "
+	}
+}
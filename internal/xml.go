@@ -0,0 +1,302 @@
+package internal
+
+import (
+	r "reflect"
+	"strings"
+	"unicode"
+)
+
+// Converts a value to XML. The root element is named after the type
+// of the value, elements of arrays and slices are enclosed in it.
+// Control characters other than line breaks and tabs are replaced
+// by U+FFFD, XML 1.0 doesn't allow them even as references.
+func ConvertToXML(o *Options, val *r.Value) string {
+	c := NewCompositeConverter(withFormat(o, formatXML), val)
+	return c.ConvertStackToString()
+}
+
+// Converts an array or slice to repeated elements named by the key
+// of Item it, or after their type if it has no key. Nested arrays
+// are enclosed in an element of their own.
+func (c *CompositeConverter) convertXMLArray(it *Item) {
+	length := it.val.Len()
+
+	if it.ix == 0 {
+		if it.val.Kind() == r.Slice && c.convertCycle(it) {
+			return
+		}
+
+		if c.isString(it.val) {
+			c.write(xmlEscape(bytesToString(it.val)))
+			c.pop()
+			return
+		}
+	}
+
+	if it.ix == length {
+		if c.options.Pretty && it.suffix != "" && length > 0 {
+			// End tag of the enclosing element is on its own line
+			c.writeXMLLine(it.indent - 1)
+		}
+
+		c.pop()
+		return
+	}
+
+	c.writeXMLLine(it.indent)
+	elem := it.val.Index(it.ix)
+	newItem := c.push(None, 0, &elem)
+	tag := it.key
+
+	if tag == "" {
+		tag = xmlTypeName(it.val.Type().Elem())
+	}
+
+	c.writeXMLStart(newItem, tag)
+
+	if _, ok := c.xmlRepeated(elem); ok {
+		newItem.indent = it.indent + 1
+	}
+
+	it.ix++
+}
+
+// Writes a field of a struct or a value of a map as an element with
+// given start tag. Arrays and slices are written as repeated elements.
+func (c *CompositeConverter) convertXMLEntry(
+	it *Item, tag string, redact bool, val *r.Value,
+) {
+	if redact {
+		c.writeXMLLine(it.indent + 1)
+		c.write("<" + tag + ">" + c.options.RedactedValue + xmlEndTag(tag))
+		it.count++
+		return
+	}
+
+	if elems, ok := c.xmlRepeated(*val); ok {
+		// Empty array has no elements to write
+		if elems.Len() > 0 {
+			c.pushEntry(it, val).key = tag
+			it.count++
+		}
+
+		return
+	}
+
+	c.writeXMLLine(it.indent + 1)
+	c.writeXMLStart(c.pushEntry(it, val), tag)
+	it.count++
+}
+
+// Returns the start tag of an element for a value of a map. Keys that
+// aren't valid names are written in an attribute of an entry element.
+func (c *CompositeConverter) formatXMLKey(key *r.Value) string {
+	return xmlEntryTag(c.formatXMLText(key))
+}
+
+// Returns a value converted on its own as escaped text.
+// Composite values are converted to JSON.
+func (c *CompositeConverter) formatXMLText(val *r.Value) string {
+	elem := c.xmlUnwrap(*val)
+	o := *c.options
+	o.format = formatDefault
+	o.Pretty = false
+
+	if IsCompositeType(&elem) && !c.hasCustomString(&elem) {
+		o.format = formatJSON
+	}
+
+	sub := NewCompositeConverter(&o, &elem)
+	return xmlEscape(sub.ConvertStackToString())
+}
+
+// Returns true if a struct field should be written as an XML attribute
+func (c *CompositeConverter) isXMLAttribute(tag fieldTag) bool {
+	return c.options.format == formatXML && tag.attr && isXMLName(tag.name)
+}
+
+// Start the root element named after the type of its value,
+// unnamed types are written as a value element. Arrays and slices
+// are enclosed in the root, their elements at every level
+// are named after their type.
+func (c *CompositeConverter) startXMLRoot(it *Item) {
+	elems, repeated := c.xmlRepeated(*it.val)
+
+	if repeated {
+		it.indent = 1
+		it.val = &elems
+	}
+
+	name := "value"
+
+	if it.val.IsValid() {
+		name = xmlTypeName(it.val.Type())
+	}
+
+	c.writeXMLStart(it, name)
+}
+
+// Write fields of a struct tagged as attributes into its start tag.
+// Nil values are omitted.
+func (c *CompositeConverter) writeXMLAttributes(it *Item) {
+	// The start tag is ended after the attributes
+	end := c.pendingSep
+	c.pendingSep = ""
+
+	for i := 0; i < it.val.NumField(); i++ {
+		tag := newFieldTag(it.typ.Field(i), c.options.TagName)
+		field := structField(it.val, i)
+
+		if !c.isXMLAttribute(tag) || tag.omit ||
+			(tag.omitEmpty && field.IsZero()) {
+			continue
+		}
+
+		value := c.options.RedactedValue
+
+		if !tag.redact {
+			if elem := c.xmlUnwrap(field); IsNil(&elem) {
+				continue
+			}
+
+			value = c.formatXMLText(&field)
+		}
+
+		c.write(" " + tag.name + "=\"" + value + "\"")
+	}
+
+	c.pendingSep = end
+}
+
+// End the start tag of the enclosing element and write a line break
+// before an element in pretty mode, unless it's the first line
+func (c *CompositeConverter) writeXMLLine(level int) {
+	if end := c.pendingSep; end != "" {
+		c.pendingSep = ""
+		c.write(end)
+	}
+
+	if c.options.Pretty && c.builder.Len() > 0 {
+		c.writeLine(level)
+	}
+}
+
+// Write the start tag of an element that encloses the value of Item it.
+// The tag is ended before the value and the end tag is written
+// when the Item is popped.
+func (c *CompositeConverter) writeXMLStart(it *Item, tag string) {
+	c.write("<" + tag)
+	c.pendingSep = ">"
+	it.suffix = xmlEndTag(tag)
+}
+
+// Unwraps interfaces and pointers of a value and returns true
+// if it's an array or slice written as repeated elements
+func (c *CompositeConverter) xmlRepeated(val r.Value) (r.Value, bool) {
+	val = c.xmlUnwrap(val)
+
+	switch val.Kind() {
+	case r.Array, r.Slice:
+		return val, !c.isString(&val) && !c.hasCustomString(&val)
+	}
+
+	return val, false
+}
+
+// Unwraps interfaces and pointers of a value unless they're nil,
+// form a cycle or are written by a custom method or a formatter
+func (c *CompositeConverter) xmlUnwrap(val r.Value) r.Value {
+	visited := map[uintptr]bool{}
+
+	for (val.Kind() == r.Interface || val.Kind() == r.Pointer) &&
+		!val.IsNil() && !c.hasCustomString(&val) {
+		if val.Kind() == r.Pointer {
+			if visited[val.Pointer()] {
+				break
+			}
+
+			visited[val.Pointer()] = true
+		}
+
+		val = val.Elem()
+	}
+
+	return val
+}
+
+// Returns true if a string is a valid name of an element or attribute
+func isXMLName(s string) bool {
+	for i, char := range s {
+		if !(unicode.IsLetter(char) || char == '_' ||
+			i > 0 && (unicode.IsDigit(char) || char == '-' || char == '.')) {
+			return false
+		}
+	}
+
+	return s != ""
+}
+
+// Returns the name of a type behind pointers if it's a valid name
+// of an element, otherwise returns value
+func xmlTypeName(aType r.Type) string {
+	for aType.Kind() == r.Pointer {
+		aType = aType.Elem()
+	}
+
+	if isXMLName(aType.Name()) {
+		return aType.Name()
+	}
+
+	return "value"
+}
+
+// Returns the end tag of an element with given start tag
+func xmlEndTag(tag string) string {
+	name, _, _ := strings.Cut(tag, " ")
+	return "</" + name + ">"
+}
+
+// Returns the start tag of an element named by escaped text.
+// Text that isn't a valid name is written in an attribute of an entry element.
+func xmlEntryTag(text string) string {
+	if isXMLName(text) {
+		return text
+	}
+
+	return "entry key=\"" + text + "\""
+}
+
+// Escapes characters that have a special meaning in XML and line breaks.
+// Characters that aren't allowed in XML are replaced by the replacement character.
+func xmlEscape(s string) string {
+	var builder strings.Builder
+
+	for _, char := range s {
+		switch char {
+		case '"':
+			builder.WriteString("&quot;")
+		case '&':
+			builder.WriteString("&amp;")
+		case '\'':
+			builder.WriteString("&apos;")
+		case '<':
+			builder.WriteString("&lt;")
+		case '>':
+			builder.WriteString("&gt;")
+		case '\t':
+			builder.WriteString("&#x9;")
+		case '\n':
+			builder.WriteString("&#xA;")
+		case '\r':
+			builder.WriteString("&#xD;")
+		default:
+			if char < 0x20 || char == 0xFFFE || char == 0xFFFF {
+				builder.WriteRune(unicode.ReplacementChar)
+			} else {
+				builder.WriteRune(char)
+			}
+		}
+	}
+
+	return builder.String()
+}
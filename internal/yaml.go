@@ -0,0 +1,209 @@
+package internal
+
+import (
+	"math"
+	r "reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Converts a value to YAML block collections
+func ConvertToYAML(o *Options, val *r.Value) string {
+	c := NewCompositeConverter(withFormat(o, formatYAML), val)
+	return c.ConvertStackToString()
+}
+
+// Converts an array or slice to a block sequence.
+// Each element starts on a new line with a dash.
+func (c *CompositeConverter) convertYAMLArray(it *Item) {
+	length := it.val.Len()
+
+	if it.ix == 0 {
+		if it.val.Kind() == r.Slice && c.convertCycle(it) {
+			return
+		}
+
+		if c.isString(it.val) {
+			c.write(c.formatYAMLString(bytesToString(it.val), it))
+			c.pop()
+			return
+		}
+
+		if length == 0 {
+			// Empty sequence can't be written in block style
+			c.write("[]")
+			c.pop()
+			return
+		}
+	}
+
+	if it.ix == length {
+		c.pop()
+		return
+	}
+
+	c.writeYAMLLine(it.indent)
+	c.write("- ")
+
+	// Block collection in the element starts on the line of the dash
+	c.inlineBlock = true
+
+	elem := it.val.Index(it.ix)
+	c.push(None, 0, &elem).indent = it.indent + 1
+	it.ix++
+}
+
+// Writes a value of a basic type as a YAML scalar. Values that have
+// no YAML equivalent are written as strings that describe them.
+func (c *CompositeConverter) convertYAMLLeaf(it *Item) {
+	val := it.val
+
+	switch val.Kind() {
+	case r.Bool:
+		c.write(c.formatBool(val))
+	case r.Float32, r.Float64:
+		c.write(c.formatYAMLFloat(val))
+	case r.Int, r.Int8, r.Int16, r.Int32, r.Int64:
+		c.write(c.formatInt(val))
+	case r.Interface, r.Invalid:
+		c.write("null")
+	case r.String:
+		c.write(c.formatYAMLString(val.String(), it))
+	case r.Uint, r.Uint8, r.Uint16, r.Uint32, r.Uint64:
+		c.write(c.formatUint(val))
+	default:
+		c.write(yamlQuote(c.ConvertToString(val)))
+	}
+}
+
+// Formats a floating-point number, infinities and NaN
+// are written as YAML special values
+func (c *CompositeConverter) formatYAMLFloat(val *r.Value) string {
+	switch f := val.Float(); {
+	case math.IsNaN(f):
+		return ".nan"
+	case math.IsInf(f, 1):
+		return ".inf"
+	case math.IsInf(f, -1):
+		return "-.inf"
+	}
+
+	return c.ConvertToString(val)
+}
+
+// Returns a key of a mapping as a YAML scalar. Composite keys
+// are converted to JSON and quoted.
+func (c *CompositeConverter) formatYAMLKey(key *r.Value) string {
+	if key.Kind() == r.Interface && !key.IsNil() {
+		elem := key.Elem()
+		key = &elem
+	}
+
+	if key.Kind() == r.String {
+		return yamlQuote(key.String())
+	}
+
+	o := *c.options
+
+	if IsCompositeType(key) {
+		o.format = formatJSON
+	}
+
+	sub := NewCompositeConverter(&o, key)
+	res := sub.ConvertStackToString()
+
+	if o.format == formatJSON && !strings.HasPrefix(res, "\"") {
+		return yamlQuote(res)
+	}
+
+	return res
+}
+
+// Formats a string as a YAML scalar. Strings with multiple lines that
+// are nested in a collection are written as literal block scalars.
+func (c *CompositeConverter) formatYAMLString(s string, it *Item) string {
+	if it.indent == 0 || !strings.Contains(s, "\n") ||
+		strings.HasPrefix(s, " ") || !isYAMLPrintable(s, true) {
+		return yamlQuote(s)
+	}
+
+	// Chomping indicator keeps the exact number of trailing line breaks
+	trimmed := strings.TrimRight(s, "\n")
+
+	switch len(s) - len(trimmed) {
+	case 0:
+		return "|-\n" + trimmed
+	case 1:
+		return "|\n" + trimmed
+	}
+
+	// The last line break is written by the next line
+	return "|+\n" + s[:len(s)-1]
+}
+
+// Write a line break before an entry of a block collection, unless
+// the collection starts on the line of a sequence dash
+func (c *CompositeConverter) writeYAMLLine(level int) {
+	c.pendingSep = ""
+
+	if c.inlineBlock {
+		c.inlineBlock = false
+		return
+	}
+
+	c.writeLine(level)
+}
+
+// Returns true if all characters of a string are printable.
+// Line breaks are allowed if lineBreaks is true.
+func isYAMLPrintable(s string, lineBreaks bool) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+
+	for _, char := range s {
+		if char == '\n' && lineBreaks {
+			continue
+		}
+
+		if !unicode.IsPrint(char) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Returns true if a string written as a plain scalar would be read
+// as a different value or type, or isn't a valid plain scalar
+func isYAMLReserved(s string) bool {
+	switch strings.ToLower(s) {
+	case "", "~", "null", "true", "false", "yes", "no", "on", "off", "y", "n":
+		return true
+	}
+
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return true
+	}
+
+	// Numbers, dates, indicators and document markers
+	if strings.ContainsRune("0123456789+-.?:,[]{}#&*!|>'\"%@`", rune(s[0])) {
+		return true
+	}
+
+	return strings.HasPrefix(s, " ") || strings.HasSuffix(s, " ") ||
+		strings.HasSuffix(s, ":") || strings.Contains(s, ": ") ||
+		strings.Contains(s, " #")
+}
+
+// Quotes a string unless it can be written as a plain scalar.
+// Double-quoted JSON strings are valid YAML scalars.
+func yamlQuote(s string) string {
+	if isYAMLReserved(s) || !isYAMLPrintable(s, false) {
+		return jsonQuote(s)
+	}
+
+	return s
+}
@@ -5,7 +5,24 @@ import ite "github.com/Matej-Chmel/go-any-to-string/internal"
 // Type alias for formatting options
 type Options = ite.Options
 
+// Type alias for symbols of a layer of a multidimensional array or slice
+type ArrayDimension = ite.ArrayDimension
+
+// Constructs symbols of each layer of an array or slice with dims
+// dimensions that Options use by default
+func NewArrayDimensions(o *Options, dims int) []ArrayDimension {
+	return ite.NewArrayDimensions(o, dims)
+}
+
 // Constructs new formatting options
 func NewOptions() *Options {
 	return ite.NewOptions()
 }
+
+// Function type that converts a Value to a string
+type FormatterType = ite.FormatterType
+
+// Registers a custom formatter for values of type T
+func RegisterFormatterFor[T any](o *Options, f func(T) string) {
+	ite.RegisterFormatterFor(o, f)
+}